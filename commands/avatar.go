@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+type avatarCommand struct{}
+
+// "Show avatar" in the context menu of a user.
+type avatarUserCommand struct{}
+
+func init() {
+	Register(avatarCommand{})
+	Register(avatarUserCommand{})
+}
+
+func (avatarCommand) Name() string {
+	return "avatar"
+}
+
+func (avatarCommand) Definition() types.ApplicationCommand {
+	options := []types.ApplicationCommandOption{
+		userOption("user", "User to display avatar for (optional)", false),
+		boolOption("show_server_profile", "Whether to show user's server profile avatar if it exists. Optional, defaults to true.", false),
+	}
+	return slashCommand("avatar", "Display user's avatar", append(options, imageFormatOptions()...)...)
+}
+
+func (avatarCommand) Handle(request *Request) (*Response, error) {
+	// Pick the user (maybe specified from command)
+	avatarUser := request.User()
+	avatarGuildMember := &request.Interaction.Member
+	if user, member, ok := request.Options.User("user"); ok {
+		avatarUser = user
+		avatarGuildMember = member
+	}
+	preferServer, ok := request.Options.Bool("show_server_profile")
+	if !ok {
+		preferServer = true
+	}
+	format, size := imageOptions(request)
+	return avatarResponse(request.Locale(), request.Interaction.GuildId, avatarUser, avatarGuildMember, preferServer, format, size), nil
+}
+
+func (avatarUserCommand) Name() string {
+	return "Show avatar"
+}
+
+func (avatarUserCommand) Definition() types.ApplicationCommand {
+	return userCommand("Show avatar")
+}
+
+func (avatarUserCommand) Handle(request *Request) (*Response, error) {
+	user, member, ok := request.TargetUser()
+	if !ok {
+		return nil, fmt.Errorf("target user %s not resolved", request.Interaction.Data.TargetId)
+	}
+	return avatarResponse(request.Locale(), request.Interaction.GuildId, user, member, true, "", defaultImageSize), nil
+}
+
+// Show the user's server profile avatar if preferred and they have one, otherwise their own or the default one.
+// Format and size only apply to avatars the user uploaded, see cdnImage.url.
+func avatarResponse(locale locale, guildId string, avatarUser types.UserData, avatarGuildMember *types.GuildMemberData, preferServer bool, format string, size int) *Response {
+	fullUser := fmt.Sprintf("%s#%s", avatarUser.Username, avatarUser.Discriminator)
+	title := locale.Sprintf("Avatar for %s", fullUser)
+
+	var response *Response
+	if preferServer && avatarGuildMember != nil && avatarGuildMember.Avatar != nil {
+		avatar := newCdnImage(fmt.Sprintf("guilds/%s/users/%s/avatars", guildId, avatarUser.Id), *avatarGuildMember.Avatar)
+		response = imageResponse(title, avatar.url(format, size), avatar.links(size))
+	} else if avatarUser.Avatar != nil {
+		avatar := newCdnImage(fmt.Sprintf("avatars/%s", avatarUser.Id), *avatarUser.Avatar)
+		response = imageResponse(title, avatar.url(format, size), avatar.links(size))
+	} else {
+		response = imageResponse(title, defaultAvatarUrl(avatarUser), "")
+	}
+
+	if decoration, ok := decorationUrl(avatarUser); ok {
+		response.Data.Embeds[0].Thumbnail.Url = decoration
+	}
+	return response
+}
+
+// Respond with an embed showing a linked image, with description below the title.
+func imageResponse(title string, imageUrl string, description string) *Response {
+	return &Response{
+		Data: types.InteractionCallbackData{
+			Embeds: []types.Embed{{
+				Title:       title,
+				Description: description,
+				Url:         imageUrl,
+				Image:       types.EmbedImage{Url: imageUrl},
+			}},
+		},
+	}
+}
@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+
+	"github.com/haplesspanda/haplessbot/rest"
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+type bannerCommand struct{}
+
+// "Show banner" in the context menu of a user.
+type bannerUserCommand struct{}
+
+func init() {
+	Register(bannerCommand{})
+	Register(bannerUserCommand{})
+}
+
+func (bannerCommand) Name() string {
+	return "banner"
+}
+
+func (bannerCommand) Definition() types.ApplicationCommand {
+	options := []types.ApplicationCommandOption{userOption("user", "User to display banner for (optional)", false)}
+	return slashCommand("banner", "Display user's banner", append(options, imageFormatOptions()...)...)
+}
+
+func (bannerCommand) Handle(request *Request) (*Response, error) {
+	bannerUserId, ok := request.Options.String("user")
+	if !ok {
+		bannerUserId = request.User().Id
+	}
+	format, size := imageOptions(request)
+	return bannerResponse(request.Ctx, request.Locale(), bannerUserId, format, size)
+}
+
+func (bannerUserCommand) Name() string {
+	return "Show banner"
+}
+
+func (bannerUserCommand) Definition() types.ApplicationCommand {
+	return userCommand("Show banner")
+}
+
+func (bannerUserCommand) Handle(request *Request) (*Response, error) {
+	return bannerResponse(request.Ctx, request.Locale(), request.Interaction.Data.TargetId, "", defaultImageSize)
+}
+
+// Banners aren't included in interactions, so this looks the user up. Users without a banner show their accent
+// color instead, if they picked one.
+func bannerResponse(ctx context.Context, locale locale, bannerUserId string, format string, size int) (*Response, error) {
+	// Execute get on user for banner URL
+	bannerUser, err := discord.GetUser(ctx, bannerUserId)
+	if err != nil {
+		return nil, wrapUserError(fmt.Errorf("failed to get user %s: %w", bannerUserId, err), "Couldn't look up that user, try again later")
+	}
+	fullUser := fmt.Sprintf("%s#%s", bannerUser.Username, bannerUser.Discriminator)
+
+	if bannerUser.Banner == nil {
+		if bannerUser.AccentColor != nil {
+			return accentColorResponse(locale.Sprintf("Accent color for %s", fullUser), *bannerUser.AccentColor), nil
+		}
+		return textResponse(locale.Sprintf("User %s has no banner!", fullUser)), nil
+	}
+
+	banner := newCdnImage(fmt.Sprintf("banners/%s", bannerUser.Id), *bannerUser.Banner)
+	return imageResponse(locale.Sprintf("Banner for %s", fullUser), banner.url(format, size), banner.links(size)), nil
+}
+
+// Size of the accent color swatch, the same shape as banners.
+var swatchWidth, swatchHeight = 600, 240
+
+// Embed with an image filled with the color, which is drawn here since Discord has none to link to.
+func accentColorResponse(title string, accentColor int) *Response {
+	swatch := image.NewRGBA(image.Rect(0, 0, swatchWidth, swatchHeight))
+	fill := color.RGBA{R: uint8(accentColor >> 16), G: uint8(accentColor >> 8), B: uint8(accentColor), A: 0xff}
+	draw.Draw(swatch, swatch.Bounds(), &image.Uniform{C: fill}, image.Point{}, draw.Src)
+	data := new(bytes.Buffer)
+	check(png.Encode(data, swatch))
+
+	return &Response{
+		Data: types.InteractionCallbackData{
+			Embeds: []types.Embed{{
+				Title:       title,
+				Description: fmt.Sprintf("#%06X", accentColor),
+				Image:       types.EmbedImage{Url: "attachment://accent.png"},
+				Color:       accentColor,
+			}},
+		},
+		Files: []rest.BinaryAttachment{{ContentType: "image/png", Name: "accent.png", Data: data.Bytes()}},
+	}
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+// Discord's image CDN, see https://discord.com/developers/docs/reference#image-formatting.
+const cdnBaseUrl = "https://cdn.discordapp.com"
+
+var imageFormats = []string{"png", "webp", "jpg", "gif"}
+
+// Sizes the CDN can resize images to.
+var imageSizes = []int{16, 32, 64, 128, 256, 512, 1024, 2048, 4096}
+
+// Default for the size option.
+const defaultImageSize = 4096
+
+// Options to pick the format and size of a user image, read back with imageOptions.
+func imageFormatOptions() []types.ApplicationCommandOption {
+	format := stringOption("format", "Image format. Optional, defaults to GIF for animated images and PNG otherwise.", false)
+	for _, name := range imageFormats {
+		format.Choices = append(format.Choices, types.ApplicationCommandOptionChoice{Name: strings.ToUpper(name), Value: name})
+	}
+	size := intOption("size", "Image size in pixels. Optional, defaults to 4096.", false)
+	for _, pixels := range imageSizes {
+		size.Choices = append(size.Choices, types.ApplicationCommandOptionChoice{Name: strconv.Itoa(pixels), Value: pixels})
+	}
+	return []types.ApplicationCommandOption{format, size}
+}
+
+// The chosen format, empty to pick one based on the image, and size.
+func imageOptions(request *Request) (string, int) {
+	format, _ := request.Options.String("format")
+	size, ok := request.Options.Int("size")
+	if !ok {
+		size = defaultImageSize
+	}
+	return format, size
+}
+
+// An image on the CDN, identified by its path without extension, e.g. "avatars/<user id>/<hash>".
+type cdnImage struct {
+	path     string
+	animated bool
+}
+
+// The image with the given hash in a CDN directory, e.g. "avatars/<user id>".
+func newCdnImage(directory string, hash string) cdnImage {
+	return cdnImage{path: fmt.Sprintf("%s/%s", directory, hash), animated: strings.HasPrefix(hash, "a_")}
+}
+
+// URL of the image in the given format, or GIF for animated images and PNG otherwise if it's empty. Only animated
+// images have a GIF, others fall back to PNG.
+func (i cdnImage) url(format string, size int) string {
+	if format == "" || (format == "gif" && !i.animated) {
+		format = "png"
+		if i.animated {
+			format = "gif"
+		}
+	}
+	url := fmt.Sprintf("%s/%s.%s?size=%d", cdnBaseUrl, i.path, format, size)
+	if format == "webp" && i.animated {
+		url += "&animated=true"
+	}
+	return url
+}
+
+// Markdown links to the image in every format it's available in.
+func (i cdnImage) links(size int) string {
+	links := make([]string, 0, len(imageFormats))
+	for _, format := range imageFormats {
+		if format == "gif" && !i.animated {
+			continue
+		}
+		links = append(links, fmt.Sprintf("[%s](%s)", strings.ToUpper(format), i.url(format, size)))
+	}
+	return strings.Join(links, " | ")
+}
+
+// The avatar Discord shows for users that haven't set one, picked from their user ID, or their discriminator for
+// users that haven't moved to the new usernames.
+func defaultAvatarUrl(user types.UserData) string {
+	var index uint64
+	if user.Discriminator == "" || user.Discriminator == "0" {
+		id, _ := strconv.ParseUint(user.Id, 10, 64)
+		index = (id >> 22) % 6
+	} else {
+		discriminator, _ := strconv.ParseUint(user.Discriminator, 10, 64)
+		index = discriminator % 5
+	}
+	return fmt.Sprintf("%s/embed/avatars/%d.png", cdnBaseUrl, index)
+}
+
+// The frame around the user's avatar, if they have one.
+func decorationUrl(user types.UserData) (string, bool) {
+	if user.AvatarDecorationData == nil {
+		return "", false
+	}
+	return fmt.Sprintf("%s/avatar-decoration-presets/%s.png", cdnBaseUrl, user.AvatarDecorationData.Asset), true
+}
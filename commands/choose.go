@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+type chooseCommand struct{}
+
+type orderCommand struct{}
+
+func init() {
+	Register(chooseCommand{})
+	Register(orderCommand{})
+	RegisterComponent("choose", func(request *ComponentRequest) (*Response, error) {
+		return choose(request.Locale(), submittedEntries(request))
+	})
+	RegisterComponent("order", func(request *ComponentRequest) (*Response, error) {
+		return order(request.Locale(), submittedEntries(request))
+	})
+}
+
+func (chooseCommand) Name() string {
+	return "choose"
+}
+
+func (chooseCommand) Definition() types.ApplicationCommand {
+	return slashCommand("choose", "Randomly choose from a list of things", entryOptions()...)
+}
+
+func (chooseCommand) Handle(request *Request) (*Response, error) {
+	entries := request.Options.Strings()
+	if len(entries) < 2 {
+		return entriesModal(request.Locale(), "choose", "Choose from", entries), nil
+	}
+	return choose(request.Locale(), entries)
+}
+
+func choose(locale locale, entries []string) (*Response, error) {
+	if len(entries) < 2 {
+		return nil, userErrorf("Give at least two entries to pick from.")
+	}
+
+	selectedOption := entries[rand.Intn(len(entries))]
+	return textResponse(locale.Sprintf("The answer is %s", selectedOption)), nil
+}
+
+func (orderCommand) Name() string {
+	return "order"
+}
+
+func (orderCommand) Definition() types.ApplicationCommand {
+	return slashCommand("order", "Randomly order a list", entryOptions()...)
+}
+
+func (orderCommand) Handle(request *Request) (*Response, error) {
+	entries := request.Options.Strings()
+	if len(entries) < 2 {
+		return entriesModal(request.Locale(), "order", "Put in order", entries), nil
+	}
+	return order(request.Locale(), entries)
+}
+
+func order(locale locale, options []string) (*Response, error) {
+	if len(options) < 2 {
+		return nil, userErrorf("Give at least two entries to order.")
+	}
+
+	result := make([]string, 0)
+	for len(options) > 0 {
+		selectedIndex := rand.Intn(len(options))
+		selectedOption := options[selectedIndex]
+		result = append(result, selectedOption)
+		options = removeIndex(options, selectedIndex)
+	}
+
+	resultString := ""
+	for _, res := range result {
+		resultString += fmt.Sprintf("\n%s", res)
+	}
+
+	return longTextResponse(locale.Sprintf("The order is %s", resultString)), nil
+}
+
+func removeIndex(input []string, i int) []string {
+	result := make([]string, 0)
+	result = append(result, input[:i]...)
+	result = append(result, input[i+1:]...)
+	return result
+}
+
+var entryOrdinals = []string{"First", "Second", "Third", "Fourth", "Fifth", "Sixth", "Seventh", "Eighth", "Ninth", "Tenth"}
+
+// Options entry1 to entry10 shared by /choose and /order. Leaving them out opens a modal to enter any number.
+func entryOptions() []types.ApplicationCommandOption {
+	options := make([]types.ApplicationCommandOption, 0, len(entryOrdinals))
+	for i, ordinal := range entryOrdinals {
+		options = append(options, stringOption(fmt.Sprintf("entry%d", i+1), fmt.Sprintf("%s entry (optional)", ordinal), false))
+	}
+	return options
+}
+
+// Modal asking for entries one per line, starting with any given as options.
+func entriesModal(locale locale, prefix string, title string, entries []string) *Response {
+	input := textInput("entries", locale.Sprintf("Entries, one per line"), types.TextInputStyleParagraph)
+	input.Value = strings.Join(entries, "\n")
+	input.MaxLength = 4000
+	return modalResponse(customId(prefix), locale.Sprintf(title), input)
+}
+
+func submittedEntries(request *ComponentRequest) []string {
+	entries := make([]string, 0)
+	for _, line := range strings.Split(request.Inputs["entries"], "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			entries = append(entries, line)
+		}
+	}
+	return entries
+}
@@ -1,543 +1,203 @@
 package commands
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"log"
 	"math/rand"
-	"net/http"
-	"os"
-	"strings"
+	"runtime/debug"
 	"time"
 
-	fe8savereader "github.com/haplesspanda/fe8savereader/format"
-	"github.com/haplesspanda/haplessbot/constants"
-	"github.com/haplesspanda/haplessbot/fe8"
 	"github.com/haplesspanda/haplessbot/rest"
 	"github.com/haplesspanda/haplessbot/types"
 )
 
 var maxContentLength = 2000
 
+var discord *rest.Client
+
+// Set the client used to talk to Discord, e.g. to point commands at a different API base URL.
+func SetClient(client *rest.Client) {
+	discord = client
+}
+
 func check(e error) {
 	if e != nil {
 		panic(e)
 	}
 }
 
-var acceptedCommands = map[string]struct{}{"ping": {}, "avatar": {}, "banner": {}, "choose": {}, "order": {}, "fe8": {}}
-
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
-// Send specified commands to discord HTTP endpoint
-func DefineCommands(commands []string) {
-	for _, element := range commands {
-		_, exists := acceptedCommands[element]
-		if !exists {
-			continue
-		}
-
-		commandFile := fmt.Sprintf("commands/def/%s.json", element)
-		dat, err := os.ReadFile(commandFile)
-		check(err)
-		log.Printf("Read command: %s", dat)
+func RunInteractionCallback(ctx context.Context, details types.InteractionCreateDetails) {
+	log.Printf("Processing %s", details.Id)
 
-		url := fmt.Sprintf("https://discord.com/api/v10/applications/%d/commands", constants.ApplicationId)
-		log.Printf("Sending command to endpoint: %s", url)
-
-		request, err := http.NewRequest("POST", url, bytes.NewBuffer(dat))
-		check(err)
-
-		body := rest.DoJsonRequest(request)
+	var state interactionState
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovered panic in interaction %s: %v\n%s", details.Id, r, debug.Stack())
+			replyWithError(ctx, details, state, fmt.Errorf("panic: %v", r))
+		}
+	}()
 
-		var bodyJson any
-		json.Unmarshal(body, &bodyJson)
-		log.Printf("Command response: %s", bodyJson)
+	var err error
+	switch details.Type {
+	case 2: // Application command
+		err = runCommand(ctx, details, &state)
+	case 3, 5: // Message component, modal submission
+		err = runComponent(ctx, details, &state)
+	default:
+		err = fmt.Errorf("unexpected interaction type %d", details.Type)
+	}
+	if err != nil {
+		replyWithError(ctx, details, state, err)
 	}
 }
 
-func RunInteractionCallback(details types.InteractionCreateDetails) {
-	var data, memberData, guildId, interactionId, interactionToken = details.Data, details.Member, details.GuildId, details.Id, details.Token
-
-	log.Printf("Processing %s", interactionId)
-	if data.Type != 1 {
-		log.Printf("Unexpected command type %d, aborting", data.Type)
-		return
+func runCommand(ctx context.Context, details types.InteractionCreateDetails, state *interactionState) error {
+	data := details.Data
+	command, ok := lookupCommand(data.Name)
+	if !ok {
+		return fmt.Errorf("unknown command %s", data.Name)
 	}
+	request := newRequest(ctx, details)
 
-	userData := memberData.User
-
-	url := fmt.Sprintf("https://discord.com/api/v10/interactions/%s/%s/callback", interactionId, interactionToken)
-	log.Println(url)
-
-	followupUrl := fmt.Sprintf("https://discord.com/api/v10/webhooks/%d/%s", constants.ApplicationId, interactionToken)
-
-	var callbackJson types.InteractionCallbackMessage
-	var attachment *rest.BinaryAttachment
-	var followupJson *types.InteractionCallbackData // TODO: Improve type, this is not correct
-	switch data.Name {
-	case "ping":
-		callbackJson = types.InteractionCallbackMessage{
-			Type: 4,
-			Data: types.InteractionCallbackData{
-				Content: "Pong",
-			},
-		}
-	case "avatar":
-		// Pick the user (maybe specified from command)
-		var avatarUser types.UserData
-		var avatarGuildMember types.GuildMemberData
-		var userOption *string
-		var preferServer = true
-		if data.Options != nil && len(data.Options) > 0 {
-			for _, option := range data.Options {
-				switch option.Name {
-				case "user":
-					stringValue := option.Value.(string)
-					userOption = &stringValue
-				case "show_server_profile":
-					preferServer = option.Value.(bool)
-				default:
-					log.Printf("Aborting, unexpected avatar option: %s", data.Options[0].Name)
-					return
-				}
-			}
-		}
-
-		if userOption != nil {
-			avatarUser = data.Resolved.Users[*userOption]
-			avatarGuildMember = data.Resolved.Members[*userOption]
-		} else {
-			avatarUser = userData
-			avatarGuildMember = memberData
-		}
+	if rejected, err := checkAllowed(ctx, command, request, state); rejected || err != nil {
+		return err
+	}
 
-		fullUser := fmt.Sprintf("%s#%s", avatarUser.Username, avatarUser.Discriminator)
+	flags := 0
+	if isPrivate(command, request) {
+		flags = types.MessageFlagEphemeral
+	}
 
-		if preferServer && avatarGuildMember.Avatar != nil {
-			var avatarExtension string
-			if strings.HasPrefix(*avatarGuildMember.Avatar, "a_") {
-				avatarExtension = "gif"
-			} else {
-				avatarExtension = "png"
-			}
-			avatarUrl := fmt.Sprintf("https://cdn.discordapp.com/guilds/%s/users/%s/avatars/%s.%s?size=4096", guildId, avatarUser.Id, *avatarGuildMember.Avatar, avatarExtension)
-			callbackJson = types.InteractionCallbackMessage{
-				Type: 4,
-				Data: types.InteractionCallbackData{
-					Embeds: []types.Embed{{
-						Title: fmt.Sprintf("Avatar for %s", fullUser),
-						Url:   avatarUrl,
-						Image: types.EmbedImage{Url: avatarUrl},
-					}},
-				},
-			}
-		} else if avatarUser.Avatar != nil {
-			var avatarExtension string
-			if strings.HasPrefix(*avatarUser.Avatar, "a_") {
-				avatarExtension = "gif"
-			} else {
-				avatarExtension = "png"
-			}
-			avatarUrl := fmt.Sprintf("https://cdn.discordapp.com/avatars/%s/%s.%s?size=4096", avatarUser.Id, *avatarUser.Avatar, avatarExtension)
-			callbackJson = types.InteractionCallbackMessage{
-				Type: 4,
-				Data: types.InteractionCallbackData{
-					Embeds: []types.Embed{{
-						Title: fmt.Sprintf("Avatar for %s", fullUser),
-						Url:   avatarUrl,
-						Image: types.EmbedImage{Url: avatarUrl},
-					}},
-				},
-			}
-		} else {
-			callbackJson = types.InteractionCallbackMessage{
-				Type: 4,
-				Data: types.InteractionCallbackData{
-					Content: fmt.Sprintf("User %s has no avatar!", fullUser),
-				},
-			}
-		}
-	case "banner":
-		var bannerUserId string
-		if data.Options != nil && len(data.Options) > 0 {
-			if data.Options[0].Name != "user" {
-				log.Printf("Aborting, unexpected banner option: %s", data.Options[0].Name)
-				return
-			}
-			bannerUserId = data.Options[0].Value.(string)
-		} else {
-			bannerUserId = userData.Id
+	if deferred, ok := command.(DeferredCommand); ok && deferred.Deferred(request.Path) {
+		// Acknowledge right away, the real response replaces the loading message once it's ready.
+		// Whether it's private has to be decided here, edits can't change it.
+		err := respond(ctx, details, types.InteractionCallbackMessage{Type: 5, Data: types.InteractionCallbackData{Flags: flags}})
+		if err != nil {
+			return fmt.Errorf("deferred callback failed: %w", err)
 		}
+		state.deferred = true
+	}
 
-		// Execute get on user for banner URL
-		getUserUrl := fmt.Sprintf("https://discord.com/api/v10/users/%s", bannerUserId)
-		request, err := http.NewRequest("GET", getUserUrl, nil)
-		check(err)
-
-		body := rest.DoJsonRequest(request)
-
-		var bannerUser types.UserData
-
-		log.Println(body)
-		json.Unmarshal(body, &bannerUser)
-		log.Println(bannerUser)
+	response, err := command.Handle(request)
+	if err != nil {
+		return fmt.Errorf("command %s failed: %w", request.Path, err)
+	}
+	if !response.Modal {
+		response.Data.Flags |= flags
+	}
+	for i := range response.Followups {
+		response.Followups[i].Flags |= flags
+	}
+	return sendResponse(ctx, details, response, state)
+}
 
-		fullUser := fmt.Sprintf("%s#%s", bannerUser.Username, bannerUser.Discriminator)
+// Apply the owner, role and cooldown checks for the command, replying with why it can't be used if one fails.
+// Returns whether it replied.
+func checkAllowed(ctx context.Context, command Command, request *Request, state *interactionState) (bool, error) {
+	if rejected, err := checkDenied(ctx, command, request, state); rejected || err != nil {
+		return rejected, err
+	}
 
-		if bannerUser.Banner == nil {
-			callbackJson = types.InteractionCallbackMessage{
-				Type: 4,
-				Data: types.InteractionCallbackData{
-					Content: fmt.Sprintf("User %s has no banner!", fullUser),
-				},
-			}
-		} else {
-			var bannerExtension string
-			if strings.HasPrefix(*bannerUser.Banner, "a_") {
-				bannerExtension = "gif"
-			} else {
-				bannerExtension = "png"
-			}
-			bannerUrl := fmt.Sprintf("https://cdn.discordapp.com/banners/%s/%s.%s?size=4096", bannerUser.Id, *bannerUser.Banner, bannerExtension)
-			callbackJson = types.InteractionCallbackMessage{
-				Type: 4,
-				Data: types.InteractionCallbackData{
-					Embeds: []types.Embed{{
-						Title: fmt.Sprintf("Banner for %s", fullUser),
-						Url:   bannerUrl,
-						Image: types.EmbedImage{Url: bannerUrl},
-					}},
-				},
-			}
-		}
-	case "choose":
-		if data.Options == nil || len(data.Options) < 2 {
-			log.Printf("Aborting, not enough parameters: %v", data.Options)
-			return
+	details := request.Interaction
+	if until, ok := checkCooldown(command, request); !ok {
+		log.Printf("%s is on cooldown for interaction %s until %s", request.Path, details.Id, until)
+		err := respond(ctx, details, cooldownResponse(request, until))
+		if err != nil {
+			return true, fmt.Errorf("cooldown reply failed: %w", err)
 		}
+		state.responded = true
+		return true, nil
+	}
+	return false, nil
+}
 
-		selectedOption := data.Options[rand.Intn(len(data.Options))]
-		callbackJson = types.InteractionCallbackMessage{
-			Type: 4,
-			Data: types.InteractionCallbackData{
-				Content: fmt.Sprintf("The answer is %s", selectedOption.Value),
-			},
-		}
-	case "order":
-		if data.Options == nil || len(data.Options) < 2 {
-			log.Printf("Aborting, not enough parameters: %v", data.Options)
-			return
-		}
+// Apply the owner and role checks for the command, replying with why it can't be used if one fails. Returns whether
+// it replied.
+func checkDenied(ctx context.Context, command Command, request *Request, state *interactionState) (bool, error) {
+	details := request.Interaction
+	if reason, ok := checkPermissions(command, request); !ok {
+		log.Printf("%s denied %s for interaction %s", interactionUser(details).Id, request.Path, details.Id)
+		err := respond(ctx, details, deniedResponse(request, reason))
+		if err != nil {
+			return true, fmt.Errorf("permission denied reply failed: %w", err)
+		}
+		state.responded = true
+		return true, nil
+	}
+	return false, nil
+}
 
-		numOptions := len(data.Options)
+// BusyResponse is the ephemeral reply for interactions dropped because too many are already waiting, or the bot is
+// shutting down.
+func BusyResponse(details types.InteractionCreateDetails) types.InteractionCallbackMessage {
+	return types.InteractionCallbackMessage{
+		Type: 4,
+		Data: types.InteractionCallbackData{
+			Content: interactionLocale(details).Sprintf("The bot is busy right now, try again in a moment."),
+			Flags:   types.MessageFlagEphemeral,
+		},
+	}
+}
 
-		options := make([]string, numOptions)
-		for index, option := range data.Options {
-			options[index] = option.Value.(string)
+// Send a handler's response, filling in the loading message if the interaction was deferred, then its followups.
+func sendResponse(ctx context.Context, details types.InteractionCreateDetails, response *Response, state *interactionState) error {
+	if state.deferred {
+		data := response.Data
+		data.Flags &^= types.MessageFlagEphemeral
+		_, err := discord.EditOriginalResponse(ctx, details.Token, data, response.Files...)
+		if err != nil {
+			return fmt.Errorf("edit original response failed: %w", err)
 		}
-
-		result := make([]string, 0)
-		for len(options) > 0 {
-			selectedIndex := rand.Intn(len(options))
-			selectedOption := options[selectedIndex]
-			result = append(result, selectedOption)
-			options = removeIndex(options, selectedIndex)
+	} else {
+		callbackType := 4
+		if response.UpdateMessage {
+			callbackType = 7
 		}
-
-		resultString := ""
-		for _, res := range result {
-			resultString += fmt.Sprintf("\n%s", res)
+		if response.Modal {
+			callbackType = 9
 		}
-
-		callbackJson = types.InteractionCallbackMessage{
-			Type: 4,
-			Data: types.InteractionCallbackData{
-				Content: fmt.Sprintf("The order is %s", resultString),
-			},
+		err := respond(ctx, details, types.InteractionCallbackMessage{Type: callbackType, Data: response.Data}, response.Files...)
+		if err != nil {
+			return fmt.Errorf("interaction callback failed: %w", err)
 		}
-	case "fe8":
-		if data.Options == nil || len(data.Options) != 1 {
-			log.Printf("Aborting, wrong parameters: %v", data.Options)
-			return
-		}
-
-		arg := data.Options[0]
-		switch arg.Name {
-		case "character":
-			if arg.Options == nil || len(arg.Options) != 1 {
-				log.Printf("Aborting, wrong parameters: %v", arg.Options)
-				return
-			}
-
-			switch arg.Options[0].Name {
-			case "info":
-				subArg := arg.Options[0]
-				if subArg.Options == nil || len(subArg.Options) != 1 {
-					log.Printf("Aborting, wrong parameters: %v", subArg.Options)
-					return
-				}
-
-				characterName := subArg.Options[0].Value.(string)
-				data, err := fe8.GetCharacterData(characterName)
-
-				if err == nil {
-					thumbnailUrl := fmt.Sprintf("attachment://%s", data.ThumbnailImage.Name)
-					attachment = &rest.BinaryAttachment{
-						ContentType: "image/png",
-						Name:        data.ThumbnailImage.Name,
-						Filename:    data.ThumbnailImage.Filename,
-					}
-					callbackJson = types.InteractionCallbackMessage{
-						Type: 4,
-						Data: types.InteractionCallbackData{
-							Embeds: []types.Embed{{
-								Title:       data.Name,
-								Description: data.Content,
-								Thumbnail: types.EmbedThumbnail{
-									Url: thumbnailUrl,
-								},
-							}},
-						},
-					}
-				} else {
-					callbackJson = types.InteractionCallbackMessage{
-						Type: 4,
-						Data: types.InteractionCallbackData{
-							Content: *err,
-						},
-					}
-				}
-			case "averagestats":
-				subArg := arg.Options[0]
-				if subArg.Options == nil || len(subArg.Options) < 2 || len(subArg.Options) > 6 {
-					log.Printf("Aborting, wrong parameters: %v", subArg.Options)
-					return
-				}
-
-				var characterName string
-				var level int
-				var promotion *string
-				var promotionLevel *int
-				var secondPromotion *string
-				var secondPromotionLevel *int
-				for _, option := range subArg.Options {
-					switch option.Name {
-					case "character":
-						characterName = option.Value.(string)
-					case "level":
-						level = int(option.Value.(float64))
-					case "promotion":
-						value := option.Value.(string)
-						promotion = &value
-					case "promotionlevel":
-						value := int(option.Value.(float64))
-						promotionLevel = &value
-					case "secondpromotion":
-						value := option.Value.(string)
-						secondPromotion = &value
-					case "secondpromotionlevel":
-						value := int(option.Value.(float64))
-						secondPromotionLevel = &value
-					default:
-						log.Printf("Unknown argument %s, aborting", option.Name)
-						return
-					}
-				}
-				data, err := fe8.GetAverageStats(characterName, level, promotion, promotionLevel, secondPromotion, secondPromotionLevel)
-
-				if err == nil {
-					thumbnailUrl := fmt.Sprintf("attachment://%s", data.ThumbnailImage.Name)
-					attachment = &rest.BinaryAttachment{
-						ContentType: "image/png",
-						Name:        data.ThumbnailImage.Name,
-						Filename:    data.ThumbnailImage.Filename,
-					}
-					callbackJson = types.InteractionCallbackMessage{
-						Type: 4,
-						Data: types.InteractionCallbackData{
-							Embeds: []types.Embed{{
-								Title:       data.Name,
-								Description: data.Content,
-								Thumbnail: types.EmbedThumbnail{
-									Url: thumbnailUrl,
-								},
-							}},
-						},
-					}
-				} else {
-					callbackJson = types.InteractionCallbackMessage{
-						Type: 4,
-						Data: types.InteractionCallbackData{
-							Content: *err,
-						},
-					}
-				}
-			default:
-				log.Printf("Unknown subcommand %s, aborting", arg.Options[0].Name)
-				return
-			}
-		case "savefile":
-			if data.Options == nil || len(data.Options) != 1 {
-				log.Printf("Aborting, wrong parameters: %v", data.Options)
-				return
-			}
-
-			switch arg.Options[0].Name {
-			case "read":
-				subArg := arg.Options[0]
-				if subArg.Options == nil || len(subArg.Options) != 1 {
-					log.Printf("Aborting, wrong parameters: %v", subArg.Options)
-					return
-				}
-
-				fileRef := subArg.Options[0].Value.(string)
-				attachment := data.Resolved.Attachments[fileRef]
-
-				response, err := http.Get(attachment.Url)
-				check(err)
-				defer response.Body.Close()
-
-				data, err := io.ReadAll(response.Body)
-				check(err)
-
-				outputBuffer := new(bytes.Buffer)
-				fe8savereader.Read(bytes.NewReader(data), outputBuffer)
-
-				outputString := outputBuffer.String()
-				if len(outputString) > maxContentLength {
-					followupJson = &types.InteractionCallbackData{
-						Content: outputString[maxContentLength:],
-					}
-					outputString = outputString[:maxContentLength]
-				}
-				// TODO: Better long message support
-
-				callbackJson = types.InteractionCallbackMessage{
-					Type: 4,
-					Data: types.InteractionCallbackData{
-						Content: outputString,
-					},
-				}
-
-				log.Printf("%s", outputBuffer)
-
-			case "compare":
-				subArg := arg.Options[0]
-				if subArg.Options == nil || len(subArg.Options) != 2 {
-					log.Printf("Aborting, wrong parameters: %v", subArg.Options)
-					return
-				}
-
-				oldFileRef := subArg.Options[0].Value.(string)
-				oldAttachment := data.Resolved.Attachments[oldFileRef]
-
-				oldResponse, err := http.Get(oldAttachment.Url)
-				check(err)
-				defer oldResponse.Body.Close()
-				oldData, err := io.ReadAll(oldResponse.Body)
-				check(err)
-
-				newFileRef := subArg.Options[1].Value.(string)
-				newAttachment := data.Resolved.Attachments[newFileRef]
-
-				newResponse, err := http.Get(newAttachment.Url)
-				check(err)
-				defer newResponse.Body.Close()
-				newData, err := io.ReadAll(newResponse.Body)
-				check(err)
-
-				outputBuffer := new(bytes.Buffer)
-				fe8savereader.Diff(bytes.NewReader(oldData), bytes.NewReader(newData), outputBuffer)
-
-				outputString := outputBuffer.String()
-				if len(outputString) > maxContentLength {
-					followupJson = &types.InteractionCallbackData{
-						Content: outputString[maxContentLength:],
-					}
-					outputString = outputString[:maxContentLength]
-				}
-				// TODO: Better long message support
-
-				callbackJson = types.InteractionCallbackMessage{
-					Type: 4,
-					Data: types.InteractionCallbackData{
-						Content: outputString,
-					},
-				}
-
-				log.Printf("%s", outputBuffer)
-			default:
-				log.Printf("Unknown subcommand %s, aborting", arg.Name)
-				return
-			}
+	}
+	state.responded = true
 
-		default:
-			log.Printf("Unknown subcommand %s, aborting", arg.Name)
-			return
+	for _, followup := range response.Followups {
+		_, err := discord.CreateFollowup(ctx, details.Token, followup)
+		if err != nil {
+			return fmt.Errorf("followup failed: %w", err)
 		}
-	default:
-		log.Printf("Unexpected command %s, aborting", data.Name)
-		return
 	}
+	return nil
+}
 
-	callbackBytes, err := json.Marshal(callbackJson)
-	check(err)
-
-	if attachment == nil {
-		request, err := http.NewRequest("POST", url, bytes.NewBuffer(callbackBytes))
-		check(err)
-
-		body := rest.DoJsonRequest(request)
-
-		var bodyJson any
-		json.Unmarshal(body, &bodyJson)
-
-		check(err)
-		log.Printf("Interaction callback response: %s", bodyJson)
-	} else {
-		body, boundary := rest.MultiPartForm(callbackBytes, *attachment)
-		request, err := http.NewRequest("POST", url, body)
-		check(err)
-
-		// dumpedRequest, err := httputil.DumpRequestOut(request, true)
-		// check(err)
-		// log.Printf("Request: %s", dumpedRequest)
-
-		responseBody := rest.DoRequest(request, fmt.Sprintf("multipart/form-data; boundary=%s", boundary))
-
-		// TODO: Merge logging with above
-		var bodyJson any
-		json.Unmarshal(responseBody, &bodyJson)
-
-		check(err)
-		log.Printf("Interaction callback response: %s", bodyJson)
-
+// Whether to reply privately, from the command's private option or else its default.
+func isPrivate(command Command, request *Request) bool {
+	if private, ok := request.Options.Bool("private"); ok {
+		return private
 	}
+	privateCommand, ok := command.(PrivateCommand)
+	return ok && privateCommand.Private(request.Path)
+}
 
-	if followupJson != nil {
-		followupBytes, err := json.Marshal(followupJson)
-		check(err)
-
-		request, err := http.NewRequest("POST", followupUrl, bytes.NewBuffer(followupBytes))
-		check(err)
-		body := rest.DoJsonRequest(request)
-
-		var bodyJson any
-		json.Unmarshal(body, &bodyJson)
-
-		check(err)
-		log.Printf("Followup response: %s", bodyJson)
+// Respond with text that may not fit in one message, sending the rest as followups.
+func longTextResponse(content string) *Response {
+	chunks := make([]string, 0)
+	for len(content) > maxContentLength {
+		chunks = append(chunks, content[:maxContentLength])
+		content = content[maxContentLength:]
 	}
-}
+	chunks = append(chunks, content)
 
-func removeIndex(input []string, i int) []string {
-	result := make([]string, 0)
-	result = append(result, input[:i]...)
-	result = append(result, input[i+1:]...)
-	return result
+	response := textResponse(chunks[0])
+	for _, chunk := range chunks[1:] {
+		response.Followups = append(response.Followups, types.InteractionCallbackData{Content: chunk})
+	}
+	return response
 }
@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+// Discord's limit on custom_id length.
+const maxCustomIdLength = 100
+
+// ComponentHandler responds to a button click, select menu choice or modal submission.
+type ComponentHandler func(request *ComponentRequest) (*Response, error)
+
+type ComponentRequest struct {
+	Ctx         context.Context
+	Interaction types.InteractionCreateDetails
+	// Values encoded into the custom_id after its prefix, see customId.
+	State []string
+	// Chosen values, for select menus.
+	Values []string
+	// Submitted text by text input custom_id, for modals.
+	Inputs map[string]string
+}
+
+// The user that used the component.
+func (r *ComponentRequest) User() types.UserData {
+	return interactionUser(r.Interaction)
+}
+
+var componentHandlers = make(map[string]ComponentHandler)
+
+// RegisterComponent routes components and modals whose custom_id starts with prefix to handler.
+func RegisterComponent(prefix string, handler ComponentHandler) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if _, exists := componentHandlers[prefix]; exists {
+		panic(fmt.Sprintf("component prefix %s registered twice", prefix))
+	}
+	componentHandlers[prefix] = handler
+}
+
+func lookupComponent(prefix string) (ComponentHandler, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	handler, ok := componentHandlers[prefix]
+	return handler, ok
+}
+
+// Build a custom_id routing to the handler registered for prefix, which gets state back in ComponentRequest.State.
+func customId(prefix string, state ...string) string {
+	parts := []string{prefix}
+	for _, value := range state {
+		parts = append(parts, url.QueryEscape(value))
+	}
+	result := strings.Join(parts, ":")
+	if len(result) > maxCustomIdLength {
+		panic(fmt.Sprintf("custom_id %s is longer than %d characters", result, maxCustomIdLength))
+	}
+	return result
+}
+
+func parseCustomId(id string) (string, []string, error) {
+	parts := strings.Split(id, ":")
+	state := make([]string, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		value, err := url.QueryUnescape(part)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid custom_id %q: %w", id, err)
+		}
+		state = append(state, value)
+	}
+	return parts[0], state, nil
+}
+
+func runComponent(ctx context.Context, details types.InteractionCreateDetails, state *interactionState) error {
+	prefix, componentState, err := parseCustomId(details.Data.CustomId)
+	if err != nil {
+		return err
+	}
+	handler, ok := lookupComponent(prefix)
+	if !ok {
+		return fmt.Errorf("no handler for component %s", details.Data.CustomId)
+	}
+
+	// Components act for the command whose response they're on, so whoever uses them needs to be allowed to run it.
+	// Its cooldown is left alone, clicks aren't new uses of the command. Modal submissions have no message, the
+	// command was checked when it opened the modal.
+	if origin := details.Message; origin != nil && origin.Interaction != nil {
+		if command, ok := lookupCommandPath(origin.Interaction.Name); ok {
+			request := &Request{Ctx: ctx, Interaction: details, Path: origin.Interaction.Name}
+			if rejected, err := checkDenied(ctx, command, request, state); rejected || err != nil {
+				return err
+			}
+		}
+	}
+
+	response, err := handler(&ComponentRequest{
+		Ctx:         ctx,
+		Interaction: details,
+		State:       componentState,
+		Values:      details.Data.Values,
+		Inputs:      modalInputs(details.Data.Components),
+	})
+	if err != nil {
+		return fmt.Errorf("component %s failed: %w", details.Data.CustomId, err)
+	}
+	return sendResponse(ctx, details, response, state)
+}
+
+// Text input values in a modal submission, by custom_id.
+func modalInputs(rows []types.Component) map[string]string {
+	result := make(map[string]string)
+	for _, row := range rows {
+		for _, component := range row.Components {
+			if component.Type == types.ComponentTypeTextInput {
+				result[component.CustomId] = component.Value
+			}
+		}
+	}
+	return result
+}
+
+// Open a modal with one text input per row. Its submission goes to the handler registered for customId's prefix.
+func modalResponse(customId string, title string, inputs ...types.Component) *Response {
+	rows := make([]types.Component, 0, len(inputs))
+	for _, input := range inputs {
+		rows = append(rows, actionRow(input))
+	}
+	return &Response{
+		Data:  types.InteractionCallbackData{CustomId: customId, Title: title, Components: rows},
+		Modal: true,
+	}
+}
+
+func actionRow(components ...types.Component) types.Component {
+	return types.Component{Type: types.ComponentTypeActionRow, Components: components}
+}
+
+func button(style int, label string, customId string) types.Component {
+	return types.Component{Type: types.ComponentTypeButton, Style: style, Label: label, CustomId: customId}
+}
+
+func stringSelect(customId string, placeholder string, options ...types.SelectOption) types.Component {
+	return types.Component{Type: types.ComponentTypeStringSelect, CustomId: customId, Placeholder: placeholder, Options: options}
+}
+
+func textInput(customId string, label string, style int) types.Component {
+	return types.Component{Type: types.ComponentTypeTextInput, CustomId: customId, Label: label, Style: style}
+}
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCustomIdRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		state []string
+		id    string
+	}{
+		{"no state", nil, "fe8"},
+		{"plain values", []string{"Eirika", "average", "10"}, "fe8:Eirika:average:10"},
+		{"separator in a value", []string{"a:b"}, "fe8:a%3Ab"},
+		{"spaces and percent signs", []string{"Great Knight", "100%"}, "fe8:Great+Knight:100%25"},
+		{"empty value", []string{"", "x"}, "fe8::x"},
+		{"unicode", []string{"Eirika ♡"}, "fe8:Eirika+%E2%99%A1"},
+	}
+	for _, test := range tests {
+		id := customId("fe8", test.state...)
+		if id != test.id {
+			t.Errorf("%s: customId = %q, want %q", test.name, id, test.id)
+		}
+		prefix, state, err := parseCustomId(id)
+		if err != nil {
+			t.Errorf("%s: parseCustomId(%q) failed: %s", test.name, id, err)
+			continue
+		}
+		want := test.state
+		if want == nil {
+			want = []string{}
+		}
+		if prefix != "fe8" || !reflect.DeepEqual(state, want) {
+			t.Errorf("%s: parseCustomId(%q) = %q, %q, want fe8, %q", test.name, id, prefix, state, want)
+		}
+	}
+}
+
+func TestParseCustomIdInvalid(t *testing.T) {
+	if _, _, err := parseCustomId("fe8:%zz"); err == nil {
+		t.Errorf("got no error for an invalid escape")
+	}
+}
+
+func TestCustomIdLengthLimit(t *testing.T) {
+	// "p:" plus the value is exactly the limit.
+	atLimit := strings.Repeat("a", maxCustomIdLength-2)
+	if id := customId("p", atLimit); len(id) != maxCustomIdLength {
+		t.Errorf("got %d characters, want %d", len(id), maxCustomIdLength)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("no panic for a custom_id over the limit")
+		}
+	}()
+	customId("p", atLimit+"a")
+}
+
+func TestCustomIdLengthCountsEscapes(t *testing.T) {
+	// Each ":" takes three characters once escaped, so this is over the limit even though the value is short.
+	defer func() {
+		if recover() == nil {
+			t.Errorf("no panic for a custom_id over the limit once escaped")
+		}
+	}()
+	customId("p", strings.Repeat(":", 40))
+}
@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/haplesspanda/haplessbot/sweep"
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+// Cooldown limits how often a command can be used.
+type Cooldown struct {
+	Duration time.Duration
+	// Who shares the cooldown: "user", "guild", "channel" or "global".
+	Per string
+}
+
+// Cooldowns by command name or full path, e.g. "banner" or "fe8 savefile read".
+var cooldowns = map[string]Cooldown{}
+
+// When each cooldown ends, by configured command name and scope.
+var cooldownUntil = map[string]time.Time{}
+var cooldownLock = sync.Mutex{}
+
+// When to sweep ended cooldowns out of cooldownUntil. Needs cooldownLock.
+var cooldownSweeps sweep.Schedule
+
+// Set the cooldowns to apply to commands.
+func SetCooldowns(commandCooldowns map[string]Cooldown) error {
+	for name, cooldown := range commandCooldowns {
+		switch cooldown.Per {
+		case "user", "guild", "channel", "global":
+		default:
+			return fmt.Errorf("invalid cooldown scope %q for %s", cooldown.Per, name)
+		}
+	}
+	cooldowns = commandCooldowns
+	return nil
+}
+
+// Start the command's cooldown if it has one, or report how long until it can be used again.
+func checkCooldown(command Command, request *Request) (time.Time, bool) {
+	// A cooldown on the command name is shared by all its subcommands.
+	name := request.Path
+	cooldown, ok := cooldowns[name]
+	if !ok {
+		name = command.Name()
+		cooldown, ok = cooldowns[name]
+	}
+	if !ok {
+		return time.Time{}, true
+	}
+
+	key := fmt.Sprintf("%s/%s:%s", name, cooldown.Per, cooldownScopeId(request.Interaction, cooldown.Per))
+	now := time.Now()
+
+	cooldownLock.Lock()
+	defer cooldownLock.Unlock()
+	if until, exists := cooldownUntil[key]; exists && now.Before(until) {
+		return until, false
+	}
+	cooldownUntil[key] = now.Add(cooldown.Duration)
+	sweep.Expired(&cooldownSweeps, cooldownUntil, cooldownEnded(now))
+	return time.Time{}, true
+}
+
+func cooldownScopeId(details types.InteractionCreateDetails, per string) string {
+	switch per {
+	case "user":
+		return interactionUser(details).Id
+	case "guild":
+		return details.GuildId
+	case "channel":
+		return details.ChannelId
+	}
+	return ""
+}
+
+// Whether a cooldown has ended, for sweeping.
+func cooldownEnded(now time.Time) func(time.Time) bool {
+	return func(until time.Time) bool { return !now.Before(until) }
+}
+
+// Ephemeral reply for a command on cooldown, with a timestamp Discord shows as e.g. "in 8 seconds".
+func cooldownResponse(request *Request, until time.Time) types.InteractionCallbackMessage {
+	// Round up, so it never says "now" while still on cooldown.
+	timestamp := until.Add(time.Second - 1).Unix()
+	return types.InteractionCallbackMessage{
+		Type: 4,
+		Data: types.InteractionCallbackData{
+			Content: request.Locale().Sprintf("Slow down! You can use `%s` again <t:%d:R>.", request.Path, timestamp),
+			Flags:   types.MessageFlagEphemeral,
+		},
+	}
+}
+
+// SaveCooldowns writes running cooldowns to filename, so they survive a restart.
+func SaveCooldowns(filename string) error {
+	cooldownLock.Lock()
+	sweep.All(cooldownUntil, cooldownEnded(time.Now()))
+	dat, err := json.Marshal(cooldownUntil)
+	cooldownLock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(filename), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, dat, 0600)
+}
+
+// LoadCooldowns restores cooldowns saved by SaveCooldowns. A missing file is not an error.
+func LoadCooldowns(filename string) error {
+	dat, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	saved := make(map[string]time.Time)
+	err = json.Unmarshal(dat, &saved)
+	if err != nil {
+		return err
+	}
+
+	cooldownLock.Lock()
+	defer cooldownLock.Unlock()
+	for key, until := range saved {
+		cooldownUntil[key] = until
+	}
+	sweep.All(cooldownUntil, cooldownEnded(time.Now()))
+	return nil
+}
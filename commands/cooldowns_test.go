@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+// Start over with only the given cooldowns.
+func resetCooldowns(t *testing.T, commandCooldowns map[string]Cooldown) {
+	t.Helper()
+	err := SetCooldowns(commandCooldowns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cooldownLock.Lock()
+	cooldownUntil = map[string]time.Time{}
+	cooldownLock.Unlock()
+	t.Cleanup(func() {
+		SetCooldowns(map[string]Cooldown{})
+		cooldownLock.Lock()
+		cooldownUntil = map[string]time.Time{}
+		cooldownLock.Unlock()
+	})
+}
+
+func cooldownRequest(path string, userId string, guildId string, channelId string) *Request {
+	return &Request{Path: path, Interaction: types.InteractionCreateDetails{
+		GuildId:   guildId,
+		ChannelId: channelId,
+		Member:    types.GuildMemberData{User: types.UserData{Id: userId}},
+	}}
+}
+
+func TestCooldownScopes(t *testing.T) {
+	first := cooldownRequest("ping", "1", "10", "100")
+	tests := []struct {
+		per    string
+		second *Request
+		want   bool
+	}{
+		{"user", cooldownRequest("ping", "1", "20", "200"), false},
+		{"user", cooldownRequest("ping", "2", "10", "100"), true},
+		{"guild", cooldownRequest("ping", "2", "10", "101"), false},
+		{"guild", cooldownRequest("ping", "1", "20", "200"), true},
+		{"channel", cooldownRequest("ping", "2", "10", "100"), false},
+		{"channel", cooldownRequest("ping", "1", "10", "101"), true},
+		{"global", cooldownRequest("ping", "2", "20", "200"), false},
+	}
+	for _, test := range tests {
+		resetCooldowns(t, map[string]Cooldown{"ping": {Duration: time.Minute, Per: test.per}})
+		if _, ok := checkCooldown(pingCommand{}, first); !ok {
+			t.Errorf("per %s: first use was on cooldown", test.per)
+		}
+		if _, ok := checkCooldown(pingCommand{}, test.second); ok != test.want {
+			t.Errorf("per %s: second use by user %s in guild %s, channel %s allowed = %t, want %t", test.per,
+				test.second.User().Id, test.second.Interaction.GuildId, test.second.Interaction.ChannelId, ok, test.want)
+		}
+	}
+}
+
+func TestCooldownPaths(t *testing.T) {
+	resetCooldowns(t, map[string]Cooldown{
+		"fe8":               {Duration: time.Minute, Per: "user"},
+		"fe8 savefile read": {Duration: time.Minute, Per: "user"},
+	})
+
+	// A cooldown on the command name is shared by the subcommands without their own.
+	if _, ok := checkCooldown(fe8Command{}, cooldownRequest("fe8 character info", "1", "10", "100")); !ok {
+		t.Errorf("first use was on cooldown")
+	}
+	if _, ok := checkCooldown(fe8Command{}, cooldownRequest("fe8 character averagestats", "1", "10", "100")); ok {
+		t.Errorf("sibling subcommand wasn't on the shared cooldown")
+	}
+	// Subcommands with their own cooldown don't share it.
+	if _, ok := checkCooldown(fe8Command{}, cooldownRequest("fe8 savefile read", "1", "10", "100")); !ok {
+		t.Errorf("subcommand with its own cooldown was on the shared one")
+	}
+	// Commands without a cooldown are always allowed.
+	if _, ok := checkCooldown(pingCommand{}, cooldownRequest("ping", "1", "10", "100")); !ok {
+		t.Errorf("command without a cooldown was on cooldown")
+	}
+}
+
+func TestCooldownExpiry(t *testing.T) {
+	resetCooldowns(t, map[string]Cooldown{"ping": {Duration: 50 * time.Millisecond, Per: "user"}})
+	request := cooldownRequest("ping", "1", "10", "100")
+
+	checkCooldown(pingCommand{}, request)
+	until, ok := checkCooldown(pingCommand{}, request)
+	if ok {
+		t.Fatalf("second use right away wasn't on cooldown")
+	}
+	if wait := time.Until(until); wait <= 0 || wait > 50*time.Millisecond {
+		t.Errorf("cooldown ends in %s, want within 50ms", wait)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := checkCooldown(pingCommand{}, request); !ok {
+		t.Errorf("still on cooldown after it ended")
+	}
+}
+
+func TestSetCooldownsRejectsUnknownScope(t *testing.T) {
+	if err := SetCooldowns(map[string]Cooldown{"ping": {Duration: time.Minute, Per: "server"}}); err == nil {
+		t.Errorf("got no error for an unknown scope")
+	}
+}
+
+func TestSaveAndLoadCooldowns(t *testing.T) {
+	resetCooldowns(t, map[string]Cooldown{"ping": {Duration: time.Minute, Per: "user"}})
+	filename := filepath.Join(t.TempDir(), "state", "cooldowns.json")
+
+	checkCooldown(pingCommand{}, cooldownRequest("ping", "1", "10", "100"))
+	cooldownLock.Lock()
+	cooldownUntil["ping/user:2"] = time.Now().Add(-time.Second)
+	cooldownLock.Unlock()
+	err := SaveCooldowns(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cooldownLock.Lock()
+	cooldownUntil = map[string]time.Time{}
+	cooldownLock.Unlock()
+	err = LoadCooldowns(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := checkCooldown(pingCommand{}, cooldownRequest("ping", "1", "10", "100")); ok {
+		t.Errorf("running cooldown wasn't restored")
+	}
+	cooldownLock.Lock()
+	_, kept := cooldownUntil["ping/user:2"]
+	cooldownLock.Unlock()
+	if kept {
+		t.Errorf("ended cooldown was saved")
+	}
+
+	if err := LoadCooldowns(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("got %v for a missing file, want nil", err)
+	}
+}
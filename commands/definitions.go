@@ -0,0 +1,54 @@
+package commands
+
+import "github.com/haplesspanda/haplessbot/types"
+
+// Builders for command definitions, see
+// https://discord.com/developers/docs/interactions/application-commands#application-command-object-application-command-option-type
+// for the option types.
+
+func slashCommand(name string, description string, options ...types.ApplicationCommandOption) types.ApplicationCommand {
+	return types.ApplicationCommand{Type: 1, Name: name, Description: description, Options: options}
+}
+
+// Command in the context menu of a user. These have no description or options.
+func userCommand(name string) types.ApplicationCommand {
+	return types.ApplicationCommand{Type: 2, Name: name}
+}
+
+// Command in the context menu of a message.
+func messageCommand(name string) types.ApplicationCommand {
+	return types.ApplicationCommand{Type: 3, Name: name}
+}
+
+func subcommandGroup(name string, description string, subcommands ...types.ApplicationCommandOption) types.ApplicationCommandOption {
+	return types.ApplicationCommandOption{Type: 2, Name: name, Description: description, Options: subcommands}
+}
+
+func subcommand(name string, description string, options ...types.ApplicationCommandOption) types.ApplicationCommandOption {
+	return types.ApplicationCommandOption{Type: 1, Name: name, Description: description, Options: options}
+}
+
+func stringOption(name string, description string, required bool) types.ApplicationCommandOption {
+	return types.ApplicationCommandOption{Type: 3, Name: name, Description: description, Required: required}
+}
+
+func intOption(name string, description string, required bool) types.ApplicationCommandOption {
+	return types.ApplicationCommandOption{Type: 4, Name: name, Description: description, Required: required}
+}
+
+func boolOption(name string, description string, required bool) types.ApplicationCommandOption {
+	return types.ApplicationCommandOption{Type: 5, Name: name, Description: description, Required: required}
+}
+
+func userOption(name string, description string, required bool) types.ApplicationCommandOption {
+	return types.ApplicationCommandOption{Type: 6, Name: name, Description: description, Required: required}
+}
+
+func attachmentOption(name string, description string, required bool) types.ApplicationCommandOption {
+	return types.ApplicationCommandOption{Type: 11, Name: name, Description: description, Required: required}
+}
+
+// Lets the user choose whether the reply is only visible to them, see isPrivate.
+func privateOption() types.ApplicationCommandOption {
+	return boolOption("private", "Only show the result to you. Optional.", false)
+}
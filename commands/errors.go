@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+// How long to keep trying to tell the user about a failure, even if the command's own context ran out.
+var errorReplyTimeout = 10 * time.Second
+
+// An error whose message is meant for the user, e.g. for invalid input. Other errors only show a generic message.
+// The message is translated to the user's language when shown, but logged in English.
+type userError struct {
+	format string
+	args   []any
+	// What went wrong behind the scenes, logged but not shown to the user.
+	cause error
+}
+
+func (e *userError) Error() string {
+	message := fmt.Sprintf(e.format, e.args...)
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %s", message, e.cause)
+	}
+	return message
+}
+
+func (e *userError) Unwrap() error {
+	return e.cause
+}
+
+func userErrorf(format string, args ...any) error {
+	return &userError{format: format, args: args}
+}
+
+// Like userErrorf, for a failure caused by err.
+func wrapUserError(err error, format string, args ...any) error {
+	return &userError{format: format, args: args, cause: err}
+}
+
+// How far an interaction got before failing, to pick how the error can still be shown.
+type interactionState struct {
+	// Acknowledged with a deferred response, the original message is "thinking...".
+	deferred bool
+	// The actual response was sent.
+	responded bool
+}
+
+// Log the failure and tell the user about it with an ephemeral message. Both mention the same ID so reports can be
+// matched to the logs.
+func replyWithError(ctx context.Context, details types.InteractionCreateDetails, state interactionState, failure error) {
+	errorId := fmt.Sprintf("%08x", rand.Uint32())
+	log.Printf("Interaction %s failed [error %s]: %s", details.Id, errorId, failure)
+
+	locale := interactionLocale(details)
+	message := locale.Sprintf("Something went wrong.")
+	var userErr *userError
+	if errors.As(failure, &userErr) {
+		message = locale.Sprintf(userErr.format, userErr.args...)
+	}
+	data := types.InteractionCallbackData{
+		Content: locale.Sprintf("%s (error ID `%s`)", message, errorId),
+		Flags:   types.MessageFlagEphemeral,
+	}
+
+	// The command's context may be what ran out, so don't let that stop the reply.
+	ctx, cancel := detach(ctx, errorReplyTimeout)
+	defer cancel()
+
+	var err error
+	switch {
+	case !state.deferred && !state.responded:
+		err = respond(ctx, details, types.InteractionCallbackMessage{Type: 4, Data: data})
+	case state.deferred && !state.responded:
+		// The loading message can't be made ephemeral, so replace it with a followup.
+		err = discord.DeleteOriginalResponse(ctx, details.Token)
+		if err == nil {
+			_, err = discord.CreateFollowup(ctx, details.Token, data)
+		}
+	default:
+		_, err = discord.CreateFollowup(ctx, details.Token, data)
+	}
+	if err != nil {
+		log.Printf("Failed to send error reply for %s [error %s]: %s", details.Id, errorId, err)
+	}
+}
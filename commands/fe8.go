@@ -0,0 +1,324 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	fe8savereader "github.com/haplesspanda/fe8savereader/format"
+	"github.com/haplesspanda/haplessbot/fe8"
+	"github.com/haplesspanda/haplessbot/rest"
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+type fe8Command struct{}
+
+// "Read FE8 save" in the context menu of a message with a savefile attached.
+type fe8SaveMessageCommand struct{}
+
+func init() {
+	Register(fe8Command{})
+	Register(fe8SaveMessageCommand{})
+	RegisterComponent("fe8", fe8Component)
+}
+
+func (fe8Command) Name() string {
+	return "fe8"
+}
+
+func (fe8Command) Definition() types.ApplicationCommand {
+	characterOption := stringOption("character", "The character to show info for", true)
+	return slashCommand("fe8", "Get info from FE8",
+		subcommandGroup("character", "Get info about a FE8 character",
+			subcommand("info", "Get basic information about a FE8 character", characterOption),
+			subcommand("averagestats", "Get average stats about a FE8 character at a certain level",
+				characterOption,
+				intOption("level", "The level at which to show average stats", true),
+				stringOption("promotion", "The class to promote the unit to (optional)", false),
+				intOption("promotionlevel", "If the unit has been promoted, their level in the promoted class (optional)", false),
+				stringOption("secondpromotion", "The second class to promote the unit to, for trainee units (optional)", false),
+				intOption("secondpromotionlevel", "If the unit has been promoted a second time, their level in the second promotion class (optional)", false),
+			),
+		),
+		subcommandGroup("savefile", "Get info about a FE8 savefile",
+			subcommand("read", "Read savefile and print a summary",
+				attachmentOption("file", "The savefile to read", true),
+				privateOption(),
+			),
+			subcommand("compare", "Read two savefiles and print the differences",
+				attachmentOption("oldfile", "The old savefile to read", true),
+				attachmentOption("newfile", "The new savefile to read", true),
+				privateOption(),
+			),
+		),
+	)
+}
+
+// Savefiles have to be downloaded first.
+func (fe8Command) Deferred(path string) bool {
+	return path == "fe8 savefile read" || path == "fe8 savefile compare"
+}
+
+func (fe8Command) Handle(request *Request) (*Response, error) {
+	return Routes{
+		"character info":         fe8CharacterInfo,
+		"character averagestats": fe8AverageStats,
+		"savefile read":          fe8SavefileRead,
+		"savefile compare":       fe8SavefileCompare,
+	}.Handle(request)
+}
+
+func fe8CharacterInfo(request *Request) (*Response, error) {
+	characterName, err := request.Options.RequireString("character")
+	if err != nil {
+		return nil, err
+	}
+
+	progression, dataErr := fe8.GetCharacterProgression(characterName)
+	if dataErr != nil {
+		return textResponse(*dataErr), nil
+	}
+	return fe8CharacterView(request.Locale(), progression, fe8View{name: "info"})
+}
+
+func fe8AverageStats(request *Request) (*Response, error) {
+	characterName, err := request.Options.RequireString("character")
+	if err != nil {
+		return nil, err
+	}
+	level, err := request.Options.RequireInt("level")
+	if err != nil {
+		return nil, err
+	}
+
+	data, dataErr := fe8.GetAverageStats(
+		characterName,
+		level,
+		request.Options.OptionalString("promotion"),
+		request.Options.OptionalInt("promotionlevel"),
+		request.Options.OptionalString("secondpromotion"),
+		request.Options.OptionalInt("secondpromotionlevel"))
+	if dataErr != nil {
+		return textResponse(*dataErr), nil
+	}
+	return characterResponse(data), nil
+}
+
+// What the buttons under /fe8 character info show: "info", "average" at a level, or "promote" to a class and level.
+type fe8View struct {
+	name      string
+	level     int
+	promotion string
+}
+
+// Component state is the character name followed by the view.
+func fe8Component(request *ComponentRequest) (*Response, error) {
+	if len(request.State) < 2 {
+		return nil, fmt.Errorf("invalid fe8 component state %v", request.State)
+	}
+	view := fe8View{name: request.State[1]}
+	var err error
+	switch {
+	case view.name == "average" && len(request.State) == 3:
+		view.level, err = strconv.Atoi(request.State[2])
+	case view.name == "promote" && len(request.State) == 4:
+		view.promotion = request.State[2]
+		view.level, err = strconv.Atoi(request.State[3])
+	case view.name == "promote" && len(request.Values) == 1:
+		// Picked from the select menu, start right after promoting.
+		view.promotion = request.Values[0]
+		view.level = 1
+	case view.name != "info":
+		err = fmt.Errorf("invalid fe8 component state %v", request.State)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	progression, dataErr := fe8.GetCharacterProgression(request.State[0])
+	if dataErr != nil {
+		return nil, userErrorf("%s", *dataErr)
+	}
+	response, err := fe8CharacterView(request.Locale(), progression, view)
+	if err != nil {
+		return nil, err
+	}
+	response.UpdateMessage = true
+	return response, nil
+}
+
+// Character info or average stats, with buttons to switch to the other views.
+func fe8CharacterView(locale locale, progression *fe8.CharacterProgression, view fe8View) (*Response, error) {
+	var data *fe8.CharacterResponse
+	var dataErr *string
+	switch view.name {
+	case "info":
+		data, dataErr = fe8.GetCharacterData(progression.Name)
+	case "average":
+		data, dataErr = fe8.GetAverageStats(progression.Name, view.level, nil, nil, nil, nil)
+	case "promote":
+		data, dataErr = fe8.GetAverageStats(progression.Name, progression.MaxLevel, &view.promotion, &view.level, nil, nil)
+	}
+	if dataErr != nil {
+		return nil, userErrorf("%s", *dataErr)
+	}
+
+	response := characterResponse(data)
+	response.Data.Components = fe8Components(locale, progression, view)
+	return response, nil
+}
+
+func fe8Components(locale locale, progression *fe8.CharacterProgression, view fe8View) []types.Component {
+	name := progression.Name
+	current := func(component types.Component, matches bool) types.Component {
+		component.Disabled = matches
+		return component
+	}
+
+	views := []types.Component{
+		current(button(types.ButtonStyleSecondary, locale.Sprintf("Info"), customId("fe8", name, "info")), view.name == "info"),
+	}
+	for _, level := range []int{10, 20} {
+		if level < progression.BaseLevel || level > progression.MaxLevel {
+			continue
+		}
+		label := locale.Sprintf("Level %d averages", level)
+		id := customId("fe8", name, "average", strconv.Itoa(level))
+		views = append(views, current(button(types.ButtonStylePrimary, label, id), view.name == "average" && view.level == level))
+	}
+	result := []types.Component{actionRow(views...)}
+
+	if len(progression.Promotions) == 0 {
+		return result
+	}
+	options := make([]types.SelectOption, 0, len(progression.Promotions))
+	for _, promotion := range progression.Promotions {
+		options = append(options, types.SelectOption{Label: promotion, Value: promotion, Default: promotion == view.promotion})
+	}
+	placeholder := locale.Sprintf("Promote at level %d to...", progression.MaxLevel)
+	result = append(result, actionRow(stringSelect(customId("fe8", name, "promote"), placeholder, options...)))
+
+	if view.name == "promote" {
+		levels := make([]types.Component, 0)
+		for _, level := range []int{1, 10, 20} {
+			label := locale.Sprintf("%s level %d", view.promotion, level)
+			id := customId("fe8", name, "promote", view.promotion, strconv.Itoa(level))
+			levels = append(levels, current(button(types.ButtonStylePrimary, label, id), view.level == level))
+		}
+		result = append(result, actionRow(levels...))
+	}
+	return result
+}
+
+// Embed with the character's portrait as thumbnail.
+func characterResponse(data *fe8.CharacterResponse) *Response {
+	thumbnailUrl := fmt.Sprintf("attachment://%s", data.ThumbnailImage.Name)
+	return &Response{
+		Data: types.InteractionCallbackData{
+			Embeds: []types.Embed{{
+				Title:       data.Name,
+				Description: data.Content,
+				Thumbnail: types.EmbedThumbnail{
+					Url: thumbnailUrl,
+				},
+			}},
+		},
+		Files: []rest.BinaryAttachment{{
+			ContentType: "image/png",
+			Name:        data.ThumbnailImage.Name,
+			Filename:    data.ThumbnailImage.Filename,
+		}},
+	}
+}
+
+func fe8SavefileRead(request *Request) (*Response, error) {
+	attachment, err := request.Options.RequireAttachment("file")
+	if err != nil {
+		return nil, err
+	}
+	return readSavefile(request.Ctx, attachment)
+}
+
+func (fe8SaveMessageCommand) Name() string {
+	return "Read FE8 save"
+}
+
+func (fe8SaveMessageCommand) Definition() types.ApplicationCommand {
+	return messageCommand("Read FE8 save")
+}
+
+func (fe8SaveMessageCommand) Deferred(path string) bool {
+	return true
+}
+
+func (fe8SaveMessageCommand) Handle(request *Request) (*Response, error) {
+	message, ok := request.TargetMessage()
+	if !ok {
+		return nil, fmt.Errorf("target message %s not resolved", request.Interaction.Data.TargetId)
+	}
+	if len(message.Attachments) == 0 {
+		return nil, userErrorf("That message has no savefile attached.")
+	}
+	return readSavefile(request.Ctx, message.Attachments[0])
+}
+
+func readSavefile(ctx context.Context, attachment types.Attachment) (*Response, error) {
+	data, err := downloadAttachment(ctx, attachment)
+	if err != nil {
+		return nil, err
+	}
+
+	outputBuffer := new(bytes.Buffer)
+	fe8savereader.Read(bytes.NewReader(data), outputBuffer)
+	log.Printf("%s", outputBuffer)
+
+	return longTextResponse(outputBuffer.String()), nil
+}
+
+func fe8SavefileCompare(request *Request) (*Response, error) {
+	oldAttachment, err := request.Options.RequireAttachment("oldfile")
+	if err != nil {
+		return nil, err
+	}
+	newAttachment, err := request.Options.RequireAttachment("newfile")
+	if err != nil {
+		return nil, err
+	}
+
+	oldData, err := downloadAttachment(request.Ctx, oldAttachment)
+	if err != nil {
+		return nil, err
+	}
+	newData, err := downloadAttachment(request.Ctx, newAttachment)
+	if err != nil {
+		return nil, err
+	}
+
+	outputBuffer := new(bytes.Buffer)
+	fe8savereader.Diff(bytes.NewReader(oldData), bytes.NewReader(newData), outputBuffer)
+	log.Printf("%s", outputBuffer)
+
+	return longTextResponse(outputBuffer.String()), nil
+}
+
+func downloadAttachment(ctx context.Context, attachment types.Attachment) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", attachment.Url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, userErrorf("Couldn't download %s (HTTP %d).", attachment.Filename, response.StatusCode)
+	}
+
+	return io.ReadAll(response.Body)
+}
@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+// Translations of response strings and command definitions by Discord locale, keyed by the English text. Strings
+// without a translation are shown in English.
+var catalogs = map[string]map[string]string{
+	"de":    catalogDe,
+	"es-ES": catalogEs,
+	"fr":    catalogFr,
+}
+
+// Locales that share another locale's catalog.
+var localeFallbacks = map[string]string{
+	"es-419": "es-ES",
+}
+
+// A Discord locale to translate responses to, see https://discord.com/developers/docs/reference#locales.
+type locale string
+
+// The language to respond in: the user's, or the guild's if the user's isn't known.
+func interactionLocale(details types.InteractionCreateDetails) locale {
+	if details.Locale != "" {
+		return locale(details.Locale)
+	}
+	return locale(details.GuildLocale)
+}
+
+// The language to respond to the command in.
+func (r *Request) Locale() locale {
+	return interactionLocale(r.Interaction)
+}
+
+// The language to respond to the component in.
+func (r *ComponentRequest) Locale() locale {
+	return interactionLocale(r.Interaction)
+}
+
+// Translate format if there's a translation for the locale, then format it like fmt.Sprintf.
+func (l locale) Sprintf(format string, args ...any) string {
+	return fmt.Sprintf(l.translate(format), args...)
+}
+
+func (l locale) translate(text string) string {
+	catalog, ok := catalogs[string(l)]
+	if !ok {
+		catalog = catalogs[localeFallbacks[string(l)]]
+	}
+	if translated, ok := catalog[text]; ok {
+		return translated
+	}
+	return text
+}
+
+// Fill in localizations for every locale with a catalog, from the English text. Slash command and option names stay
+// English so they're the same for everyone, only context menu command names and choices are translated.
+func localizeCommand(command types.ApplicationCommand) types.ApplicationCommand {
+	if command.Type == 2 || command.Type == 3 {
+		command.NameLocalizations = localizations(command.Name)
+	}
+	command.DescriptionLocalizations = localizations(command.Description)
+	command.Options = localizeOptions(command.Options)
+	return command
+}
+
+func localizeOptions(options []types.ApplicationCommandOption) []types.ApplicationCommandOption {
+	if options == nil {
+		return nil
+	}
+	result := make([]types.ApplicationCommandOption, 0, len(options))
+	for _, option := range options {
+		option.DescriptionLocalizations = localizations(option.Description)
+		if option.Choices != nil {
+			choices := make([]types.ApplicationCommandOptionChoice, 0, len(option.Choices))
+			for _, choice := range option.Choices {
+				choice.NameLocalizations = localizations(choice.Name)
+				choices = append(choices, choice)
+			}
+			option.Choices = choices
+		}
+		option.Options = localizeOptions(option.Options)
+		result = append(result, option)
+	}
+	return result
+}
+
+// Translations of text by locale, or nil if there are none.
+func localizations(text string) map[string]string {
+	if text == "" {
+		return nil
+	}
+	var result map[string]string
+	add := func(name string, catalog map[string]string) {
+		if translated, ok := catalog[text]; ok {
+			if result == nil {
+				result = make(map[string]string)
+			}
+			result[name] = translated
+		}
+	}
+	for name, catalog := range catalogs {
+		add(name, catalog)
+	}
+	for name, fallback := range localeFallbacks {
+		add(name, catalogs[fallback])
+	}
+	return result
+}
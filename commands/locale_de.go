@@ -0,0 +1,91 @@
+package commands
+
+// German.
+var catalogDe = map[string]string{
+	// Errors and checks
+	"Something went wrong.":                               "Etwas ist schiefgelaufen.",
+	"%s (error ID `%s`)":                                  "%s (Fehler-ID `%s`)",
+	"Missing or invalid option `%s`.":                     "Fehlende oder ungültige Option `%s`.",
+	"Only the bot owner can use this command!":            "Nur der Besitzer des Bots kann diesen Befehl benutzen!",
+	"You don't have permission to use this command here.": "Du hast hier keine Berechtigung für diesen Befehl.",
+	"You don't have a role that can use this command.":    "Du hast keine Rolle, die diesen Befehl benutzen darf.",
+	"The bot is busy right now, try again in a moment.":   "Der Bot ist gerade beschäftigt, versuch es gleich noch einmal.",
+	"Slow down! You can use `%s` again <t:%d:R>.":         "Langsam! Du kannst `%s` <t:%d:R> wieder benutzen.",
+
+	// /avatar and /banner
+	"Display user's avatar":                 "Zeigt den Avatar eines Nutzers",
+	"User to display avatar for (optional)": "Nutzer, dessen Avatar gezeigt wird (optional)",
+	"Whether to show user's server profile avatar if it exists. Optional, defaults to true.": "Ob der Serverprofil-Avatar gezeigt wird, falls vorhanden. Optional, standardmäßig ja.",
+	"Show avatar":   "Avatar zeigen",
+	"Avatar for %s": "Avatar von %s",
+	"Image format. Optional, defaults to GIF for animated images and PNG otherwise.": "Bildformat. Optional, standardmäßig GIF für animierte Bilder und sonst PNG.",
+	"Image size in pixels. Optional, defaults to 4096.":                              "Bildgröße in Pixeln. Optional, standardmäßig 4096.",
+	"Accent color for %s":                         "Akzentfarbe von %s",
+	"Display user's banner":                       "Zeigt das Banner eines Nutzers",
+	"User to display banner for (optional)":       "Nutzer, dessen Banner gezeigt wird (optional)",
+	"Show banner":                                 "Banner zeigen",
+	"Banner for %s":                               "Banner von %s",
+	"User %s has no banner!":                      "%s hat kein Banner!",
+	"Couldn't look up that user, try again later": "Nutzer konnte nicht abgerufen werden, versuch es später noch einmal",
+
+	// /choose and /order
+	"Randomly choose from a list of things":   "Wählt zufällig etwas aus einer Liste",
+	"Randomly order a list":                   "Bringt eine Liste in zufällige Reihenfolge",
+	"First entry (optional)":                  "Erster Eintrag (optional)",
+	"Second entry (optional)":                 "Zweiter Eintrag (optional)",
+	"Third entry (optional)":                  "Dritter Eintrag (optional)",
+	"Fourth entry (optional)":                 "Vierter Eintrag (optional)",
+	"Fifth entry (optional)":                  "Fünfter Eintrag (optional)",
+	"Sixth entry (optional)":                  "Sechster Eintrag (optional)",
+	"Seventh entry (optional)":                "Siebter Eintrag (optional)",
+	"Eighth entry (optional)":                 "Achter Eintrag (optional)",
+	"Ninth entry (optional)":                  "Neunter Eintrag (optional)",
+	"Tenth entry (optional)":                  "Zehnter Eintrag (optional)",
+	"Choose from":                             "Auswählen aus",
+	"Put in order":                            "Ordnen",
+	"Entries, one per line":                   "Einträge, einer pro Zeile",
+	"Give at least two entries to pick from.": "Gib mindestens zwei Einträge zur Auswahl an.",
+	"Give at least two entries to order.":     "Gib mindestens zwei Einträge zum Ordnen an.",
+	"The answer is %s":                        "Die Antwort ist %s",
+	"The order is %s":                         "Die Reihenfolge ist %s",
+
+	// /fe8
+	"Get info from FE8":                                                                                 "Infos aus FE8",
+	"Get info about a FE8 character":                                                                    "Infos zu einer FE8-Figur",
+	"Get basic information about a FE8 character":                                                       "Grundlegende Infos zu einer FE8-Figur",
+	"The character to show info for":                                                                    "Die Figur, zu der Infos gezeigt werden",
+	"Get average stats about a FE8 character at a certain level":                                        "Durchschnittswerte einer FE8-Figur auf einem bestimmten Level",
+	"The level at which to show average stats":                                                          "Das Level, für das Durchschnittswerte gezeigt werden",
+	"The class to promote the unit to (optional)":                                                       "Die Klasse, in die die Einheit befördert wird (optional)",
+	"If the unit has been promoted, their level in the promoted class (optional)":                       "Level in der beförderten Klasse, falls befördert (optional)",
+	"The second class to promote the unit to, for trainee units (optional)":                             "Die zweite Beförderungsklasse, für Rekruten (optional)",
+	"If the unit has been promoted a second time, their level in the second promotion class (optional)": "Level in der zweiten Beförderungsklasse, falls zweimal befördert (optional)",
+	"Get info about a FE8 savefile":                                                                     "Infos zu einem FE8-Spielstand",
+	"Read savefile and print a summary":                                                                 "Liest einen Spielstand und fasst ihn zusammen",
+	"The savefile to read":                                                                              "Der zu lesende Spielstand",
+	"Read two savefiles and print the differences":                                                      "Liest zwei Spielstände und zeigt die Unterschiede",
+	"The old savefile to read":                                                                          "Der alte Spielstand",
+	"The new savefile to read":                                                                          "Der neue Spielstand",
+	"Read FE8 save":                                                                                     "FE8-Spielstand lesen",
+	"Only show the result to you. Optional.":                                                            "Das Ergebnis nur dir zeigen. Optional.",
+	"Info":                                                                                              "Info",
+	"Level %d averages":                                                                                 "Durchschnitt auf Level %d",
+	"Promote at level %d to...":                                                                         "Auf Level %d befördern zu...",
+	"%s level %d":                                                                                       "%s Level %d",
+	"That message has no savefile attached.":                                                            "An dieser Nachricht hängt kein Spielstand.",
+	"Couldn't download %s (HTTP %d).":                                                                   "%s konnte nicht heruntergeladen werden (HTTP %d).",
+
+	// /ping and /status
+	"Ping the bot":                      "Pingt den Bot",
+	"Set the bot's status (owner only)": "Setzt den Status des Bots (nur Besitzer)",
+	"Status text to show. Leave empty to go back to the rotating status (optional)": "Anzuzeigender Statustext. Leer lassen für den wechselnden Status (optional)",
+	"Kind of activity to show. Optional, defaults to a custom status.":              "Art der Aktivität. Optional, standardmäßig ein eigener Status.",
+	"Playing":                               "Spielt",
+	"Listening to":                          "Hört",
+	"Watching":                              "Schaut",
+	"Custom":                                "Eigener Status",
+	"Competing in":                          "Tritt an in",
+	"Status reset":                          "Status zurückgesetzt",
+	"Status set to %s":                      "Status auf %s gesetzt",
+	"Failed to set status, try again later": "Status konnte nicht gesetzt werden, versuch es später noch einmal",
+}
@@ -0,0 +1,91 @@
+package commands
+
+// Spanish, for both es-ES and es-419.
+var catalogEs = map[string]string{
+	// Errors and checks
+	"Something went wrong.":                               "Algo salió mal.",
+	"%s (error ID `%s`)":                                  "%s (ID de error `%s`)",
+	"Missing or invalid option `%s`.":                     "Falta la opción `%s` o no es válida.",
+	"Only the bot owner can use this command!":            "¡Solo el dueño del bot puede usar este comando!",
+	"You don't have permission to use this command here.": "No tienes permiso para usar este comando aquí.",
+	"You don't have a role that can use this command.":    "No tienes ningún rol que pueda usar este comando.",
+	"The bot is busy right now, try again in a moment.":   "El bot está ocupado ahora mismo, inténtalo de nuevo en un momento.",
+	"Slow down! You can use `%s` again <t:%d:R>.":         "¡Más despacio! Podrás usar `%s` de nuevo <t:%d:R>.",
+
+	// /avatar and /banner
+	"Display user's avatar":                 "Muestra el avatar de un usuario",
+	"User to display avatar for (optional)": "Usuario cuyo avatar mostrar (opcional)",
+	"Whether to show user's server profile avatar if it exists. Optional, defaults to true.": "Si mostrar el avatar del perfil del servidor, si existe. Opcional, sí por defecto.",
+	"Show avatar":   "Mostrar avatar",
+	"Avatar for %s": "Avatar de %s",
+	"Image format. Optional, defaults to GIF for animated images and PNG otherwise.": "Formato de imagen. Opcional, GIF para imágenes animadas y PNG si no por defecto.",
+	"Image size in pixels. Optional, defaults to 4096.":                              "Tamaño de imagen en píxeles. Opcional, 4096 por defecto.",
+	"Accent color for %s":                         "Color de acento de %s",
+	"Display user's banner":                       "Muestra el banner de un usuario",
+	"User to display banner for (optional)":       "Usuario cuyo banner mostrar (opcional)",
+	"Show banner":                                 "Mostrar banner",
+	"Banner for %s":                               "Banner de %s",
+	"User %s has no banner!":                      "¡%s no tiene banner!",
+	"Couldn't look up that user, try again later": "No se pudo consultar ese usuario, inténtalo más tarde",
+
+	// /choose and /order
+	"Randomly choose from a list of things":   "Elige al azar de una lista",
+	"Randomly order a list":                   "Ordena una lista al azar",
+	"First entry (optional)":                  "Primera entrada (opcional)",
+	"Second entry (optional)":                 "Segunda entrada (opcional)",
+	"Third entry (optional)":                  "Tercera entrada (opcional)",
+	"Fourth entry (optional)":                 "Cuarta entrada (opcional)",
+	"Fifth entry (optional)":                  "Quinta entrada (opcional)",
+	"Sixth entry (optional)":                  "Sexta entrada (opcional)",
+	"Seventh entry (optional)":                "Séptima entrada (opcional)",
+	"Eighth entry (optional)":                 "Octava entrada (opcional)",
+	"Ninth entry (optional)":                  "Novena entrada (opcional)",
+	"Tenth entry (optional)":                  "Décima entrada (opcional)",
+	"Choose from":                             "Elegir entre",
+	"Put in order":                            "Ordenar",
+	"Entries, one per line":                   "Entradas, una por línea",
+	"Give at least two entries to pick from.": "Da al menos dos entradas para elegir.",
+	"Give at least two entries to order.":     "Da al menos dos entradas para ordenar.",
+	"The answer is %s":                        "La respuesta es %s",
+	"The order is %s":                         "El orden es %s",
+
+	// /fe8
+	"Get info from FE8":                                                                                 "Información de FE8",
+	"Get info about a FE8 character":                                                                    "Información sobre un personaje de FE8",
+	"Get basic information about a FE8 character":                                                       "Información básica sobre un personaje de FE8",
+	"The character to show info for":                                                                    "El personaje del que mostrar información",
+	"Get average stats about a FE8 character at a certain level":                                        "Estadísticas medias de un personaje de FE8 en cierto nivel",
+	"The level at which to show average stats":                                                          "El nivel para el que mostrar estadísticas medias",
+	"The class to promote the unit to (optional)":                                                       "La clase a la que promocionar la unidad (opcional)",
+	"If the unit has been promoted, their level in the promoted class (optional)":                       "Si la unidad promocionó, su nivel en la nueva clase (opcional)",
+	"The second class to promote the unit to, for trainee units (optional)":                             "La segunda clase de promoción, para reclutas (opcional)",
+	"If the unit has been promoted a second time, their level in the second promotion class (optional)": "Si promocionó dos veces, su nivel en la segunda clase (opcional)",
+	"Get info about a FE8 savefile":                                                                     "Información sobre una partida guardada de FE8",
+	"Read savefile and print a summary":                                                                 "Lee una partida guardada y muestra un resumen",
+	"The savefile to read":                                                                              "La partida guardada a leer",
+	"Read two savefiles and print the differences":                                                      "Lee dos partidas guardadas y muestra las diferencias",
+	"The old savefile to read":                                                                          "La partida guardada antigua",
+	"The new savefile to read":                                                                          "La partida guardada nueva",
+	"Read FE8 save":                                                                                     "Leer partida de FE8",
+	"Only show the result to you. Optional.":                                                            "Mostrar el resultado solo a ti. Opcional.",
+	"Info":                                                                                              "Info",
+	"Level %d averages":                                                                                 "Medias en nivel %d",
+	"Promote at level %d to...":                                                                         "Promocionar en nivel %d a...",
+	"%s level %d":                                                                                       "%s nivel %d",
+	"That message has no savefile attached.":                                                            "Ese mensaje no tiene una partida guardada adjunta.",
+	"Couldn't download %s (HTTP %d).":                                                                   "No se pudo descargar %s (HTTP %d).",
+
+	// /ping and /status
+	"Ping the bot":                      "Hace ping al bot",
+	"Set the bot's status (owner only)": "Cambia el estado del bot (solo el dueño)",
+	"Status text to show. Leave empty to go back to the rotating status (optional)": "Texto de estado. Déjalo vacío para volver al estado rotativo (opcional)",
+	"Kind of activity to show. Optional, defaults to a custom status.":              "Tipo de actividad. Opcional, por defecto un estado personalizado.",
+	"Playing":                               "Jugando a",
+	"Listening to":                          "Escuchando",
+	"Watching":                              "Viendo",
+	"Custom":                                "Personalizado",
+	"Competing in":                          "Compitiendo en",
+	"Status reset":                          "Estado restablecido",
+	"Status set to %s":                      "Estado cambiado a %s",
+	"Failed to set status, try again later": "No se pudo cambiar el estado, inténtalo más tarde",
+}
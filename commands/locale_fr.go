@@ -0,0 +1,91 @@
+package commands
+
+// French.
+var catalogFr = map[string]string{
+	// Errors and checks
+	"Something went wrong.":                               "Une erreur s'est produite.",
+	"%s (error ID `%s`)":                                  "%s (ID d'erreur `%s`)",
+	"Missing or invalid option `%s`.":                     "Option `%s` manquante ou invalide.",
+	"Only the bot owner can use this command!":            "Seul le propriétaire du bot peut utiliser cette commande !",
+	"You don't have permission to use this command here.": "Tu n'as pas la permission d'utiliser cette commande ici.",
+	"You don't have a role that can use this command.":    "Tu n'as aucun rôle qui peut utiliser cette commande.",
+	"The bot is busy right now, try again in a moment.":   "Le bot est occupé pour le moment, réessaie dans un instant.",
+	"Slow down! You can use `%s` again <t:%d:R>.":         "Doucement ! Tu pourras réutiliser `%s` <t:%d:R>.",
+
+	// /avatar and /banner
+	"Display user's avatar":                 "Affiche l'avatar d'un utilisateur",
+	"User to display avatar for (optional)": "Utilisateur dont afficher l'avatar (facultatif)",
+	"Whether to show user's server profile avatar if it exists. Optional, defaults to true.": "Afficher l'avatar du profil de serveur s'il existe. Facultatif, oui par défaut.",
+	"Show avatar":   "Afficher l'avatar",
+	"Avatar for %s": "Avatar de %s",
+	"Image format. Optional, defaults to GIF for animated images and PNG otherwise.": "Format d'image. Facultatif, GIF pour les images animées et PNG sinon par défaut.",
+	"Image size in pixels. Optional, defaults to 4096.":                              "Taille de l'image en pixels. Facultatif, 4096 par défaut.",
+	"Accent color for %s":                         "Couleur d'accent de %s",
+	"Display user's banner":                       "Affiche la bannière d'un utilisateur",
+	"User to display banner for (optional)":       "Utilisateur dont afficher la bannière (facultatif)",
+	"Show banner":                                 "Afficher la bannière",
+	"Banner for %s":                               "Bannière de %s",
+	"User %s has no banner!":                      "%s n'a pas de bannière !",
+	"Couldn't look up that user, try again later": "Impossible de trouver cet utilisateur, réessaie plus tard",
+
+	// /choose and /order
+	"Randomly choose from a list of things":   "Choisit au hasard dans une liste",
+	"Randomly order a list":                   "Met une liste dans un ordre aléatoire",
+	"First entry (optional)":                  "Première entrée (facultatif)",
+	"Second entry (optional)":                 "Deuxième entrée (facultatif)",
+	"Third entry (optional)":                  "Troisième entrée (facultatif)",
+	"Fourth entry (optional)":                 "Quatrième entrée (facultatif)",
+	"Fifth entry (optional)":                  "Cinquième entrée (facultatif)",
+	"Sixth entry (optional)":                  "Sixième entrée (facultatif)",
+	"Seventh entry (optional)":                "Septième entrée (facultatif)",
+	"Eighth entry (optional)":                 "Huitième entrée (facultatif)",
+	"Ninth entry (optional)":                  "Neuvième entrée (facultatif)",
+	"Tenth entry (optional)":                  "Dixième entrée (facultatif)",
+	"Choose from":                             "Choisir parmi",
+	"Put in order":                            "Mettre en ordre",
+	"Entries, one per line":                   "Entrées, une par ligne",
+	"Give at least two entries to pick from.": "Donne au moins deux entrées parmi lesquelles choisir.",
+	"Give at least two entries to order.":     "Donne au moins deux entrées à ordonner.",
+	"The answer is %s":                        "La réponse est %s",
+	"The order is %s":                         "L'ordre est %s",
+
+	// /fe8
+	"Get info from FE8":                                                                                 "Infos sur FE8",
+	"Get info about a FE8 character":                                                                    "Infos sur un personnage de FE8",
+	"Get basic information about a FE8 character":                                                       "Infos de base sur un personnage de FE8",
+	"The character to show info for":                                                                    "Le personnage dont afficher les infos",
+	"Get average stats about a FE8 character at a certain level":                                        "Stats moyennes d'un personnage de FE8 à un certain niveau",
+	"The level at which to show average stats":                                                          "Le niveau pour lequel afficher les stats moyennes",
+	"The class to promote the unit to (optional)":                                                       "La classe de promotion de l'unité (facultatif)",
+	"If the unit has been promoted, their level in the promoted class (optional)":                       "Si l'unité est promue, son niveau dans la classe promue (facultatif)",
+	"The second class to promote the unit to, for trainee units (optional)":                             "La deuxième classe de promotion, pour les recrues (facultatif)",
+	"If the unit has been promoted a second time, their level in the second promotion class (optional)": "Si promue deux fois, son niveau dans la deuxième classe (facultatif)",
+	"Get info about a FE8 savefile":                                                                     "Infos sur une sauvegarde de FE8",
+	"Read savefile and print a summary":                                                                 "Lit une sauvegarde et en affiche un résumé",
+	"The savefile to read":                                                                              "La sauvegarde à lire",
+	"Read two savefiles and print the differences":                                                      "Lit deux sauvegardes et affiche les différences",
+	"The old savefile to read":                                                                          "L'ancienne sauvegarde",
+	"The new savefile to read":                                                                          "La nouvelle sauvegarde",
+	"Read FE8 save":                                                                                     "Lire la sauvegarde FE8",
+	"Only show the result to you. Optional.":                                                            "N'afficher le résultat que pour toi. Facultatif.",
+	"Info":                                                                                              "Infos",
+	"Level %d averages":                                                                                 "Moyennes au niveau %d",
+	"Promote at level %d to...":                                                                         "Promouvoir au niveau %d en...",
+	"%s level %d":                                                                                       "%s niveau %d",
+	"That message has no savefile attached.":                                                            "Ce message n'a pas de sauvegarde en pièce jointe.",
+	"Couldn't download %s (HTTP %d).":                                                                   "Impossible de télécharger %s (HTTP %d).",
+
+	// /ping and /status
+	"Ping the bot":                      "Ping le bot",
+	"Set the bot's status (owner only)": "Change le statut du bot (propriétaire uniquement)",
+	"Status text to show. Leave empty to go back to the rotating status (optional)": "Texte du statut. Laisser vide pour revenir au statut tournant (facultatif)",
+	"Kind of activity to show. Optional, defaults to a custom status.":              "Type d'activité. Facultatif, statut personnalisé par défaut.",
+	"Playing":                               "Joue à",
+	"Listening to":                          "Écoute",
+	"Watching":                              "Regarde",
+	"Custom":                                "Personnalisé",
+	"Competing in":                          "Participe à",
+	"Status reset":                          "Statut réinitialisé",
+	"Status set to %s":                      "Statut changé en %s",
+	"Failed to set status, try again later": "Impossible de changer le statut, réessaie plus tard",
+}
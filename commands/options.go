@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"math"
+
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+// Options gives typed access to the options a (sub)command was invoked with. Accessors report false
+// if an option is missing or doesn't have the expected type.
+type Options struct {
+	options  []types.Option
+	resolved types.ResolvedEntities
+}
+
+func (o Options) find(name string) (types.Option, bool) {
+	for _, option := range o.options {
+		if option.Name == name {
+			return option, true
+		}
+	}
+	return types.Option{}, false
+}
+
+func (o Options) Has(name string) bool {
+	_, ok := o.find(name)
+	return ok
+}
+
+func (o Options) String(name string) (string, bool) {
+	option, ok := o.find(name)
+	if !ok {
+		return "", false
+	}
+	value, ok := option.Value.(string)
+	return value, ok
+}
+
+func (o Options) Int(name string) (int, bool) {
+	option, ok := o.find(name)
+	if !ok {
+		return 0, false
+	}
+	// JSON numbers decode as float64.
+	value, ok := option.Value.(float64)
+	if !ok || value != math.Trunc(value) {
+		return 0, false
+	}
+	return int(value), true
+}
+
+func (o Options) Bool(name string) (bool, bool) {
+	option, ok := o.find(name)
+	if !ok {
+		return false, false
+	}
+	value, ok := option.Value.(bool)
+	return value, ok
+}
+
+// User returns the resolved user for a user option, and their guild member data if they are in the guild.
+func (o Options) User(name string) (types.UserData, *types.GuildMemberData, bool) {
+	userId, ok := o.String(name)
+	if !ok {
+		return types.UserData{}, nil, false
+	}
+	return o.resolveUser(userId)
+}
+
+// Look up a user, and their server profile if they're a member, in the interaction's resolved data.
+func (o Options) resolveUser(userId string) (types.UserData, *types.GuildMemberData, bool) {
+	user, ok := o.resolved.Users[userId]
+	if !ok {
+		return types.UserData{}, nil, false
+	}
+	member, isMember := o.resolved.Members[userId]
+	if !isMember {
+		return user, nil, true
+	}
+	member.User = user
+	return user, &member, true
+}
+
+func (o Options) Attachment(name string) (types.Attachment, bool) {
+	attachmentId, ok := o.String(name)
+	if !ok {
+		return types.Attachment{}, false
+	}
+	attachment, ok := o.resolved.Attachments[attachmentId]
+	return attachment, ok
+}
+
+// Values of all string options, in the order given.
+func (o Options) Strings() []string {
+	result := make([]string, 0, len(o.options))
+	for _, option := range o.options {
+		if value, ok := option.Value.(string); ok {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func (o Options) RequireString(name string) (string, error) {
+	value, ok := o.String(name)
+	if !ok {
+		return "", missingOption(name)
+	}
+	return value, nil
+}
+
+func (o Options) RequireInt(name string) (int, error) {
+	value, ok := o.Int(name)
+	if !ok {
+		return 0, missingOption(name)
+	}
+	return value, nil
+}
+
+func (o Options) RequireAttachment(name string) (types.Attachment, error) {
+	value, ok := o.Attachment(name)
+	if !ok {
+		return types.Attachment{}, missingOption(name)
+	}
+	return value, nil
+}
+
+// Pointer to a string option's value, nil if missing.
+func (o Options) OptionalString(name string) *string {
+	value, ok := o.String(name)
+	if !ok {
+		return nil
+	}
+	return &value
+}
+
+// Pointer to an integer option's value, nil if missing.
+func (o Options) OptionalInt(name string) *int {
+	value, ok := o.Int(name)
+	if !ok {
+		return nil
+	}
+	return &value
+}
+
+func missingOption(name string) error {
+	return userErrorf("Missing or invalid option `%s`.", name)
+}
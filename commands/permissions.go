@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+var owners = map[string]struct{}{}
+
+// Role IDs allowed to use a command (any one of them is enough), by command name or full path.
+var commandRoles = map[string][]string{}
+
+// Set the users allowed to run owner-only commands. Owners also skip role and permission checks.
+func SetOwners(userIds []string) {
+	owners = make(map[string]struct{})
+	for _, userId := range userIds {
+		owners[userId] = struct{}{}
+	}
+}
+
+// Limit commands to members with one of the given roles, by command name or full path.
+func SetCommandRoles(roles map[string][]string) {
+	commandRoles = roles
+}
+
+// Check whether the user may run the command, returning why not if they may not.
+//
+// Discord already hides commands from members without the definition's default_member_permissions, but clients can
+// be out of date, so the member's permissions from the interaction are checked again here.
+func checkPermissions(command Command, request *Request) (string, bool) {
+	if _, isOwner := owners[request.User().Id]; isOwner {
+		return "", true
+	}
+	if ownerOnly, ok := command.(OwnerOnlyCommand); ok && ownerOnly.OwnerOnly(request.Path) {
+		return "Only the bot owner can use this command!", false
+	}
+
+	// Outside of servers there are no roles or permissions to check against.
+	inGuild := request.Interaction.GuildId != ""
+	member := request.Interaction.Member
+	if permissions := command.Definition().DefaultMemberPermissions; permissions != nil {
+		required, err := strconv.ParseUint(*permissions, 10, 64)
+		check(err)
+		if !inGuild || !hasPermissions(member.Permissions, required) {
+			return "You don't have permission to use this command here.", false
+		}
+	}
+
+	roles, ok := commandRoles[request.Path]
+	if !ok {
+		roles, ok = commandRoles[command.Name()]
+	}
+	if ok && (!inGuild || !hasAnyRole(member.Roles, roles)) {
+		return "You don't have a role that can use this command.", false
+	}
+	return "", true
+}
+
+// Whether a permissions bitfield from Discord includes all of required. Administrator includes everything, and is
+// the only way to use commands that require "0".
+func hasPermissions(permissions string, required uint64) bool {
+	granted, err := strconv.ParseUint(permissions, 10, 64)
+	if err != nil {
+		return false
+	}
+	if granted&types.PermissionAdministrator != 0 {
+		return true
+	}
+	return required != 0 && granted&required == required
+}
+
+func hasAnyRole(memberRoles []string, allowed []string) bool {
+	for _, role := range memberRoles {
+		for _, allowedRole := range allowed {
+			if role == allowedRole {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Ephemeral reply for a user that can't run a command.
+func deniedResponse(request *Request, reason string) types.InteractionCallbackMessage {
+	return types.InteractionCallbackMessage{
+		Type: 4,
+		Data: types.InteractionCallbackData{Content: request.Locale().Sprintf(reason), Flags: types.MessageFlagEphemeral},
+	}
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+func TestHasPermissions(t *testing.T) {
+	manageMessages := uint64(1 << 13)
+	tests := []struct {
+		name        string
+		permissions string
+		required    uint64
+		want        bool
+	}{
+		{"has the permission", "8192", manageMessages, true},
+		{"has more than required", "8224", manageMessages, true},
+		{"missing the permission", "32", manageMessages, false},
+		{"administrator", "8", manageMessages, true},
+		{"admin only without administrator", "8192", 0, false},
+		{"admin only with administrator", "8", 0, true},
+		{"unparseable", "", manageMessages, false},
+	}
+	for _, test := range tests {
+		if got := hasPermissions(test.permissions, test.required); got != test.want {
+			t.Errorf("%s: hasPermissions(%q, %d) = %v, want %v", test.name, test.permissions, test.required, got, test.want)
+		}
+	}
+}
+
+func TestCheckPermissions(t *testing.T) {
+	SetOwners([]string{"1"})
+	SetCommandRoles(map[string][]string{"fe8 savefile read": {"10"}})
+	defer SetOwners(nil)
+	defer SetCommandRoles(map[string][]string{})
+
+	request := func(path string, userId string, guildId string, permissions string, roles ...string) *Request {
+		return &Request{Path: path, Interaction: types.InteractionCreateDetails{
+			GuildId: guildId,
+			Member:  types.GuildMemberData{User: types.UserData{Id: userId}, Permissions: permissions, Roles: roles},
+		}}
+	}
+	tests := []struct {
+		name    string
+		command Command
+		request *Request
+		want    bool
+	}{
+		{"owner runs owner-only command", statusCommand{}, request("status", "1", "100", "0"), true},
+		{"admin runs owner-only command", statusCommand{}, request("status", "2", "100", "8"), false},
+		{"anyone runs open command", pingCommand{}, request("ping", "2", "100", "0"), true},
+		{"member with role", fe8Command{}, request("fe8 savefile read", "2", "100", "0", "10"), true},
+		{"member without role", fe8Command{}, request("fe8 savefile read", "2", "100", "0", "11"), false},
+		{"role needed outside servers", fe8Command{}, request("fe8 savefile read", "2", "", ""), false},
+		{"other subcommand needs no role", fe8Command{}, request("fe8 character info", "2", "100", "0"), true},
+		{"owner skips roles", fe8Command{}, request("fe8 savefile read", "1", "100", "0"), true},
+	}
+	for _, test := range tests {
+		if _, got := checkPermissions(test.command, test.request); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package commands
+
+import "github.com/haplesspanda/haplessbot/types"
+
+type pingCommand struct{}
+
+func init() {
+	Register(pingCommand{})
+}
+
+func (pingCommand) Name() string {
+	return "ping"
+}
+
+func (pingCommand) Definition() types.ApplicationCommand {
+	return slashCommand("ping", "Ping the bot")
+}
+
+func (pingCommand) Handle(request *Request) (*Response, error) {
+	return textResponse("Pong"), nil
+}
@@ -0,0 +1,165 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/haplesspanda/haplessbot/rest"
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+// Command is a slash command, or a user or message context menu command, the bot can register with Discord and
+// respond to.
+type Command interface {
+	Name() string
+	Definition() types.ApplicationCommand
+	Handle(request *Request) (*Response, error)
+}
+
+// DeferredCommand is implemented by commands that can take longer than the 3 seconds Discord allows for a response.
+// Deferred ones acknowledge immediately (showing "thinking...") and edit in their response once it's ready.
+type DeferredCommand interface {
+	// Whether to defer the (sub)command with the given path, e.g. "fe8 savefile read".
+	Deferred(path string) bool
+}
+
+// PrivateCommand is implemented by commands that reply so only the user running them can see it, unless they set
+// the private option to false.
+type PrivateCommand interface {
+	// Whether the (sub)command with the given path replies privately by default.
+	Private(path string) bool
+}
+
+// OwnerOnlyCommand is implemented by operational commands only the bot's owners may run, see SetOwners.
+type OwnerOnlyCommand interface {
+	// Whether the (sub)command with the given path is owner only.
+	OwnerOnly(path string) bool
+}
+
+// Handler handles a single (sub)command.
+type Handler func(request *Request) (*Response, error)
+
+// Routes dispatches to handlers by subcommand path, e.g. "character info" for /fe8 character info.
+type Routes map[string]Handler
+
+func (routes Routes) Handle(request *Request) (*Response, error) {
+	handler, ok := routes[request.Subcommand]
+	if !ok {
+		return nil, fmt.Errorf("unknown subcommand %q", request.Path)
+	}
+	return handler(request)
+}
+
+type Request struct {
+	Ctx         context.Context
+	Interaction types.InteractionCreateDetails
+	// Full name of the invoked (sub)command, e.g. "fe8 character info".
+	Path string
+	// Path below the command name, e.g. "character info". Empty for commands without subcommands.
+	Subcommand string
+	// Options of the invoked (sub)command.
+	Options Options
+}
+
+// The user that ran the command.
+func (r *Request) User() types.UserData {
+	return interactionUser(r.Interaction)
+}
+
+// For user commands, the user that was right-clicked.
+func (r *Request) TargetUser() (types.UserData, *types.GuildMemberData, bool) {
+	return r.Options.resolveUser(r.Interaction.Data.TargetId)
+}
+
+// For message commands, the message that was right-clicked.
+func (r *Request) TargetMessage() (types.Message, bool) {
+	message, ok := r.Interaction.Data.Resolved.Messages[r.Interaction.Data.TargetId]
+	return message, ok
+}
+
+func interactionUser(details types.InteractionCreateDetails) types.UserData {
+	if details.User != nil {
+		return *details.User
+	}
+	return details.Member.User
+}
+
+// Response to send for a command, as a channel message (type 4) unless deferred.
+type Response struct {
+	Data  types.InteractionCallbackData
+	Files []rest.BinaryAttachment
+	// For components, replace the message the component is on (type 7) instead of sending a new one.
+	UpdateMessage bool
+	// Open a modal (type 9) described by Data instead of sending a message, see modalResponse.
+	Modal bool
+	// Extra messages sent after the response, e.g. for output too long for one message.
+	Followups []types.InteractionCallbackData
+}
+
+// Respond with just a message.
+func textResponse(content string) *Response {
+	return &Response{Data: types.InteractionCallbackData{Content: content}}
+}
+
+var registry = make(map[string]Command)
+var registryLock = sync.RWMutex{}
+
+// Register makes a command available to SyncCommands and RunInteractionCallback.
+func Register(command Command) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if _, exists := registry[command.Name()]; exists {
+		panic(fmt.Sprintf("command %s registered twice", command.Name()))
+	}
+	registry[command.Name()] = command
+}
+
+func lookupCommand(name string) (Command, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	command, ok := registry[name]
+	return command, ok
+}
+
+// The command a full path such as "fe8 character info" belongs to.
+func lookupCommandPath(path string) (Command, bool) {
+	// Context menu command names can have spaces in them.
+	if command, ok := lookupCommand(path); ok {
+		return command, true
+	}
+	name, _, _ := strings.Cut(path, " ")
+	return lookupCommand(name)
+}
+
+// Registered commands, sorted by name.
+func registeredCommands() []Command {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	result := make([]Command, 0, len(registry))
+	for _, command := range registry {
+		result = append(result, command)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+	return result
+}
+
+func newRequest(ctx context.Context, details types.InteractionCreateDetails) *Request {
+	data := details.Data
+	path := []string{data.Name}
+	options := data.Options
+	for len(options) == 1 && (options[0].Type == 1 || options[0].Type == 2) {
+		path = append(path, options[0].Name)
+		options = options[0].Options
+	}
+
+	return &Request{
+		Ctx:         ctx,
+		Interaction: details,
+		Path:        strings.Join(path, " "),
+		Subcommand:  strings.Join(path[1:], " "),
+		Options:     Options{options: options, resolved: data.Resolved},
+	}
+}
@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"context"
+	"time"
+
+	"github.com/haplesspanda/haplessbot/rest"
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+// InitialResponder sends the first response to an interaction in place of the callback endpoint, e.g. as the
+// body of an HTTP interaction request.
+type InitialResponder func(response types.InteractionCallbackMessage, files []rest.BinaryAttachment) error
+
+type responderKey struct{}
+
+// WithInitialResponder makes RunInteractionCallback send its initial response through responder.
+func WithInitialResponder(ctx context.Context, responder InitialResponder) context.Context {
+	return context.WithValue(ctx, responderKey{}, responder)
+}
+
+// Send the initial response to an interaction.
+func respond(ctx context.Context, details types.InteractionCreateDetails, response types.InteractionCallbackMessage, files ...rest.BinaryAttachment) error {
+	if responder, ok := ctx.Value(responderKey{}).(InitialResponder); ok {
+		return responder(response, files)
+	}
+	return discord.CreateInteractionResponse(ctx, details.Id, details.Token, response, files...)
+}
+
+// A fresh context with the same initial responder, for replies that should go out even if ctx is done.
+func detach(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	detached := context.Background()
+	if responder, ok := ctx.Value(responderKey{}).(InitialResponder); ok {
+		detached = WithInitialResponder(detached, responder)
+	}
+	return context.WithTimeout(detached, timeout)
+}
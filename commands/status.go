@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"log"
+
+	"github.com/haplesspanda/haplessbot/gateway"
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+type statusCommand struct{}
+
+func init() {
+	Register(statusCommand{})
+}
+
+func (statusCommand) Name() string {
+	return "status"
+}
+
+func (statusCommand) Definition() types.ApplicationCommand {
+	typeOption := intOption("type", "Kind of activity to show. Optional, defaults to a custom status.", false)
+	typeOption.Choices = []types.ApplicationCommandOptionChoice{
+		{Name: "Playing", Value: types.ActivityTypePlaying},
+		{Name: "Listening to", Value: types.ActivityTypeListening},
+		{Name: "Watching", Value: types.ActivityTypeWatching},
+		{Name: "Custom", Value: types.ActivityTypeCustom},
+		{Name: "Competing in", Value: types.ActivityTypeCompeting},
+	}
+	command := slashCommand("status", "Set the bot's status (owner only)",
+		stringOption("text", "Status text to show. Leave empty to go back to the rotating status (optional)", false),
+		typeOption,
+	)
+	// Hidden from everyone but server admins, see OwnerOnly for who can actually run it.
+	adminOnly := "0"
+	command.DefaultMemberPermissions = &adminOnly
+	return command
+}
+
+// Status changes are only interesting to the owner setting them.
+func (statusCommand) Private(path string) bool {
+	return true
+}
+
+func (statusCommand) OwnerOnly(path string) bool {
+	return true
+}
+
+func (statusCommand) Handle(request *Request) (*Response, error) {
+	text, hasText := request.Options.String("text")
+	activityType, ok := request.Options.Int("type")
+	if !ok {
+		activityType = types.ActivityTypeCustom
+	}
+
+	var activity *types.Activity
+	var content string
+	if !hasText {
+		content = request.Locale().Sprintf("Status reset")
+	} else if activityType == types.ActivityTypeCustom {
+		activity = &types.Activity{Name: "Custom Status", Type: activityType, State: text}
+		content = request.Locale().Sprintf("Status set to %s", text)
+	} else {
+		activity = &types.Activity{Name: text, Type: activityType}
+		content = request.Locale().Sprintf("Status set to %s", text)
+	}
+
+	err := gateway.SetCustomActivity(activity)
+	if err != nil {
+		log.Printf("Failed to set status: %s", err)
+		content = request.Locale().Sprintf("Failed to set status, try again later")
+	}
+	return textResponse(content), nil
+}
@@ -0,0 +1,240 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+// Guild IDs by command name, for commands that are only registered in those guilds instead of globally.
+var guildCommands = map[string][]string{}
+
+// Set which commands are only available in certain guilds, e.g. admin commands for one server.
+func SetGuildCommands(commandGuilds map[string][]string) {
+	guildCommands = commandGuilds
+}
+
+// SyncCommands makes the commands registered with Discord match the registry, printing what changes.
+// Commands set up with SetGuildCommands go to their guilds, the rest are global. If guildId is set, every command
+// goes to that guild instead, which applies instantly and is handy while working on commands.
+// With dryRun the changes are only printed.
+func SyncCommands(ctx context.Context, guildId string, dryRun bool) error {
+	for name := range guildCommands {
+		if _, ok := lookupCommand(name); !ok {
+			return fmt.Errorf("unknown command %s in guild commands", name)
+		}
+	}
+
+	all := make([]types.ApplicationCommand, 0)
+	global := make([]types.ApplicationCommand, 0)
+	byGuild := make(map[string][]types.ApplicationCommand)
+	for _, command := range registeredCommands() {
+		definition := localizeCommand(command.Definition())
+		all = append(all, definition)
+		guilds, guildOnly := guildCommands[command.Name()]
+		if !guildOnly {
+			global = append(global, definition)
+		}
+		for _, guild := range guilds {
+			byGuild[guild] = append(byGuild[guild], definition)
+		}
+	}
+
+	if guildId != "" {
+		return syncScope(ctx, guildId, all, dryRun)
+	}
+
+	err := syncScope(ctx, "", global, dryRun)
+	if err != nil {
+		return err
+	}
+	guilds := make([]string, 0, len(byGuild))
+	for guild := range byGuild {
+		guilds = append(guilds, guild)
+	}
+	sort.Strings(guilds)
+	for _, guild := range guilds {
+		err = syncScope(ctx, guild, byGuild[guild], dryRun)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sync the commands of one guild, or the global ones if guildId is empty.
+func syncScope(ctx context.Context, guildId string, desired []types.ApplicationCommand, dryRun bool) error {
+	scope := "global"
+	if guildId != "" {
+		scope = fmt.Sprintf("guild %s", guildId)
+	}
+
+	current, err := discord.GetGuildCommands(ctx, guildId)
+	if err != nil {
+		return fmt.Errorf("failed to get registered %s commands: %w", scope, err)
+	}
+
+	changes := diffCommands(current, desired)
+	if len(changes) == 0 {
+		fmt.Printf("No changes to %s commands\n", scope)
+		log.Printf("No changes to %s commands, %d registered", scope, len(current))
+		return nil
+	}
+	fmt.Printf("Changes to %s commands:\n", scope)
+	for _, change := range changes {
+		fmt.Println(change)
+		log.Printf("Command change (%s): %s", scope, change)
+	}
+	if dryRun {
+		fmt.Println("Dry run, not applying changes")
+		return nil
+	}
+
+	result, err := discord.BulkOverwriteGuildCommands(ctx, guildId, desired)
+	if err != nil {
+		return fmt.Errorf("failed to overwrite %s commands: %w", scope, err)
+	}
+	fmt.Printf("Synced %d %s commands\n", len(result), scope)
+	log.Printf("Synced %d %s commands", len(result), scope)
+	return nil
+}
+
+// Describe how to get from the current commands to the desired ones, one line per change.
+func diffCommands(current []types.ApplicationCommand, desired []types.ApplicationCommand) []string {
+	existing := make(map[string]types.ApplicationCommand)
+	for _, command := range current {
+		existing[commandKey(command)] = command
+	}
+
+	changes := make([]string, 0)
+	for _, command := range desired {
+		key := commandKey(command)
+		old, ok := existing[key]
+		delete(existing, key)
+		if !ok {
+			changes = append(changes, fmt.Sprintf("+ %s", command.Name))
+			continue
+		}
+		for _, field := range diffFields(normalizeCommand(old), normalizeCommand(command)) {
+			changes = append(changes, fmt.Sprintf("~ %s: %s", command.Name, field))
+		}
+	}
+
+	removed := make([]string, 0)
+	for _, command := range existing {
+		removed = append(removed, fmt.Sprintf("- %s", command.Name))
+	}
+	sort.Strings(removed)
+	return append(changes, removed...)
+}
+
+// Names are only unique per command type.
+func commandKey(command types.ApplicationCommand) string {
+	return fmt.Sprintf("%d/%s", normalizeCommand(command).Type, command.Name)
+}
+
+// Drop the fields Discord fills in so definitions can be compared to registered commands.
+func normalizeCommand(command types.ApplicationCommand) types.ApplicationCommand {
+	command.Id = ""
+	command.ApplicationId = ""
+	command.GuildId = ""
+	command.Version = ""
+	if command.Type == 0 {
+		command.Type = 1
+	}
+	return command
+}
+
+// Names of the fields that differ between old and new, with nested options as e.g. "options.character.info".
+func diffFields(old types.ApplicationCommand, new types.ApplicationCommand) []string {
+	oldOptions, newOptions := old.Options, new.Options
+	old.Options, new.Options = nil, nil
+
+	result := diffJson("", old, new)
+	return append(result, diffOptions("options", oldOptions, newOptions)...)
+}
+
+func diffOptions(path string, old []types.ApplicationCommandOption, new []types.ApplicationCommandOption) []string {
+	result := make([]string, 0)
+	if sharedOrder(old, new) != sharedOrder(new, old) {
+		result = append(result, fmt.Sprintf("%s order changed", path))
+	}
+
+	existing := make(map[string]types.ApplicationCommandOption)
+	for _, option := range old {
+		existing[option.Name] = option
+	}
+	for _, option := range new {
+		optionPath := fmt.Sprintf("%s.%s", path, option.Name)
+		oldOption, ok := existing[option.Name]
+		delete(existing, option.Name)
+		if !ok {
+			result = append(result, fmt.Sprintf("%s added", optionPath))
+			continue
+		}
+
+		oldChildren, newChildren := oldOption.Options, option.Options
+		oldOption.Options, option.Options = nil, nil
+		result = append(result, diffJson(optionPath, oldOption, option)...)
+		result = append(result, diffOptions(optionPath, oldChildren, newChildren)...)
+	}
+
+	removed := make([]string, 0)
+	for name := range existing {
+		removed = append(removed, fmt.Sprintf("%s.%s removed", path, name))
+	}
+	sort.Strings(removed)
+	return append(result, removed...)
+}
+
+// Names of the options that also appear in other, in order.
+func sharedOrder(options []types.ApplicationCommandOption, other []types.ApplicationCommandOption) string {
+	present := make(map[string]struct{})
+	for _, option := range other {
+		present[option.Name] = struct{}{}
+	}
+	names := make([]string, 0)
+	for _, option := range options {
+		if _, ok := present[option.Name]; ok {
+			names = append(names, option.Name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Compare the JSON fields of two values, reporting e.g. "options.character description changed".
+func diffJson(path string, old any, new any) []string {
+	oldFields, newFields := jsonFields(old), jsonFields(new)
+	names := make([]string, 0)
+	for name := range oldFields {
+		names = append(names, name)
+	}
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	result := make([]string, 0)
+	for _, name := range names {
+		if string(oldFields[name]) != string(newFields[name]) {
+			result = append(result, strings.TrimSpace(fmt.Sprintf("%s %s changed", path, name)))
+		}
+	}
+	return result
+}
+
+func jsonFields(value any) map[string]json.RawMessage {
+	data, err := json.Marshal(value)
+	check(err)
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	check(err)
+	return fields
+}
@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+func TestDiffCommands(t *testing.T) {
+	ping := types.ApplicationCommand{Type: 1, Name: "ping", Description: "Pong"}
+	// As Discord returns it, with the fields it fills in.
+	registeredPing := types.ApplicationCommand{Id: "1", ApplicationId: "42", Version: "7", Type: 1, Name: "ping", Description: "Pong"}
+	adminOnly := "0"
+
+	tests := []struct {
+		name    string
+		current []types.ApplicationCommand
+		desired []types.ApplicationCommand
+		want    []string
+	}{
+		{"unchanged", []types.ApplicationCommand{registeredPing}, []types.ApplicationCommand{ping}, []string{}},
+		{"type defaults to slash command", []types.ApplicationCommand{registeredPing}, []types.ApplicationCommand{{Name: "ping", Description: "Pong"}}, []string{}},
+		{"added", nil, []types.ApplicationCommand{ping}, []string{"+ ping"}},
+		{"removed, sorted", []types.ApplicationCommand{{Type: 1, Name: "b"}, {Type: 1, Name: "a"}}, nil, []string{"- a", "- b"}},
+		{"description changed", []types.ApplicationCommand{registeredPing}, []types.ApplicationCommand{{Type: 1, Name: "ping", Description: "Ping!"}}, []string{"~ ping: description changed"}},
+		{"permissions changed", []types.ApplicationCommand{registeredPing}, []types.ApplicationCommand{{Type: 1, Name: "ping", Description: "Pong", DefaultMemberPermissions: &adminOnly}}, []string{"~ ping: default_member_permissions changed"}},
+		{
+			"same name, different type",
+			[]types.ApplicationCommand{{Type: 2, Name: "Show avatar"}},
+			[]types.ApplicationCommand{{Type: 3, Name: "Show avatar"}},
+			[]string{"+ Show avatar", "- Show avatar"},
+		},
+	}
+	for _, test := range tests {
+		if got := diffCommands(test.current, test.desired); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDiffOptions(t *testing.T) {
+	character := types.ApplicationCommandOption{Type: 3, Name: "character", Description: "Who"}
+	level := types.ApplicationCommandOption{Type: 4, Name: "level", Description: "Level"}
+	info := types.ApplicationCommandOption{Type: 1, Name: "info", Description: "Info", Options: []types.ApplicationCommandOption{character}}
+
+	tests := []struct {
+		name string
+		old  []types.ApplicationCommandOption
+		new  []types.ApplicationCommandOption
+		want []string
+	}{
+		{"unchanged", []types.ApplicationCommandOption{character, level}, []types.ApplicationCommandOption{character, level}, []string{}},
+		{"added", []types.ApplicationCommandOption{character}, []types.ApplicationCommandOption{character, level}, []string{"options.level added"}},
+		{"removed", []types.ApplicationCommandOption{character, level}, []types.ApplicationCommandOption{character}, []string{"options.level removed"}},
+		{"reordered", []types.ApplicationCommandOption{character, level}, []types.ApplicationCommandOption{level, character}, []string{"options order changed"}},
+		{
+			"field changed",
+			[]types.ApplicationCommandOption{character},
+			[]types.ApplicationCommandOption{{Type: 3, Name: "character", Description: "Who", Required: true}},
+			[]string{"options.character required changed"},
+		},
+		{
+			"nested change",
+			[]types.ApplicationCommandOption{info},
+			[]types.ApplicationCommandOption{{Type: 1, Name: "info", Description: "Info", Options: []types.ApplicationCommandOption{{Type: 3, Name: "character", Description: "Which character"}}}},
+			[]string{"options.info.character description changed"},
+		},
+		{
+			"nested added",
+			[]types.ApplicationCommandOption{info},
+			[]types.ApplicationCommandOption{{Type: 1, Name: "info", Description: "Info", Options: []types.ApplicationCommandOption{character, level}}},
+			[]string{"options.info.level added"},
+		},
+	}
+	for _, test := range tests {
+		if got := diffOptions("options", test.old, test.new); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+
+	"github.com/haplesspanda/haplessbot/rest"
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+type Config struct {
+	// Address to serve the Interactions Endpoint URL on, e.g. ":8080". If set, interactions are received over
+	// HTTP instead of the gateway.
+	InteractionsAddress string `json:"interactions_address"`
+	// Application public key from the developer portal, used to verify HTTP interactions.
+	PublicKey string `json:"public_key"`
+	// Discord REST API to talk to, e.g. a local fake for testing.
+	ApiBaseUrl string `json:"api_base_url"`
+	// Gateway to connect to. Looked up from the API if empty.
+	GatewayUrl string `json:"gateway_url"`
+	// Gateway intents to request, by name (e.g. "guilds", "guild_members").
+	Intents []string `json:"intents"`
+	// User IDs allowed to run operational commands such as /status.
+	Owners   []string       `json:"owners"`
+	Presence PresenceConfig `json:"presence"`
+	// Role IDs allowed to use a command, by command name or full path. Members need any one of them.
+	CommandRoles map[string][]string `json:"command_roles"`
+	// Commands registered only in the listed guild IDs instead of globally, by command name.
+	GuildCommands map[string][]string `json:"guild_commands"`
+	// How often commands can be used, by command name or full path such as "fe8 savefile read".
+	Cooldowns map[string]CooldownConfig `json:"cooldowns"`
+	// Where to keep running cooldowns over restarts. Cooldowns are only kept in memory if empty.
+	CooldownFile string `json:"cooldown_file"`
+	// How long to keep users looked up over REST. Zero to always look users up.
+	UserCacheTtlSeconds int `json:"user_cache_ttl_seconds"`
+	// Where to save the gateway session on shutdown so restarts can resume it. Empty to always identify.
+	SessionFile string `json:"session_file"`
+	// How long to let running commands finish when shutting down.
+	ShutdownTimeoutSeconds int `json:"shutdown_timeout_seconds"`
+	// Number of commands that can run at once, and how many more can wait for a free worker.
+	Workers          int `json:"workers"`
+	WorkerQueueDepth int `json:"worker_queue_depth"`
+	// How long a single command may take before its context is cancelled.
+	InteractionTimeoutSeconds int `json:"interaction_timeout_seconds"`
+}
+
+type PresenceConfig struct {
+	// One of online, idle, dnd or invisible.
+	Status     string           `json:"status"`
+	Activities []types.Activity `json:"activities"`
+	// How often to switch to the next activity, zero to never rotate.
+	RotateIntervalSeconds int `json:"rotate_interval_seconds"`
+}
+
+type CooldownConfig struct {
+	Seconds int `json:"seconds"`
+	// Who shares the cooldown: "user" (default), "guild", "channel" or "global".
+	Per string `json:"per"`
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		ApiBaseUrl: rest.DefaultBaseUrl,
+		Intents:    []string{"guilds"},
+		Presence: PresenceConfig{
+			Status: "online",
+		},
+		UserCacheTtlSeconds:       300,
+		SessionFile:               "state/session.json",
+		ShutdownTimeoutSeconds:    10,
+		Workers:                   4,
+		WorkerQueueDepth:          32,
+		InteractionTimeoutSeconds: 30,
+	}
+}
+
+// Load reads the config file, falling back to defaults for anything missing. A missing file is not an error.
+func Load(filename string) (*Config, error) {
+	result := defaultConfig()
+
+	dat, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("No config file at %s, using defaults", filename)
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(dat, result)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Loaded config from %s: %+v", filename, *result)
+	return result, nil
+}
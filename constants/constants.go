@@ -16,7 +16,8 @@ func check(e error) {
 	}
 }
 
-func init() {
+// Load reads the bot's credentials from the secret directory. Call it before talking to Discord.
+func Load() {
 	token, err := os.ReadFile("secret/token")
 	check(err)
 	TokenId = strings.TrimSpace(string(token))
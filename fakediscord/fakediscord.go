@@ -0,0 +1,312 @@
+// Package fakediscord is an in-process stand-in for the Discord REST API and gateway, for running the bot
+// end to end without network access. Point config's api_base_url and gateway_url (or rest.Client.BaseUrl and
+// gateway.Options.GatewayUrl) at ApiUrl and GatewayUrl, send interactions with SendInteraction, and check the
+// replies with WaitForResponse.
+package fakediscord
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+// A request the bot made to the fake API.
+type Request struct {
+	Method string
+	// Path below the API base, e.g. /interactions/123/token/callback
+	Path string
+	// Interaction or webhook token, if the path has one.
+	Token   string
+	Payload json.RawMessage
+	// Uploaded files by filename.
+	Files map[string][]byte
+}
+
+type Server struct {
+	server *httptest.Server
+
+	lock     sync.Mutex
+	changed  *sync.Cond
+	requests []Request
+	users    map[string]types.UserData
+	files    map[string][]byte
+	// Registered commands by guild ID, "" for global ones.
+	commands map[string][]types.ApplicationCommand
+
+	gateway gatewayState
+}
+
+func New() *Server {
+	s := &Server{
+		users:    make(map[string]types.UserData),
+		files:    make(map[string][]byte),
+		commands: make(map[string][]types.ApplicationCommand),
+	}
+	s.changed = sync.NewCond(&s.lock)
+	s.gateway.sessions = make(map[string]int)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v10/", s.handleApi)
+	mux.HandleFunc("/gateway", s.handleGateway)
+	mux.HandleFunc("/files/", s.handleFile)
+	s.server = httptest.NewServer(mux)
+	return s
+}
+
+func (s *Server) Close() {
+	s.gateway.closeConnection()
+	s.server.Close()
+}
+
+// Base URL for the REST API.
+func (s *Server) ApiUrl() string {
+	return s.server.URL + "/api/v10"
+}
+
+func (s *Server) GatewayUrl() string {
+	return "ws" + strings.TrimPrefix(s.server.URL, "http") + "/gateway"
+}
+
+// AddUser makes a user available from GET /users/{id}.
+func (s *Server) AddUser(user types.UserData) {
+	s.lock.Lock()
+	s.users[user.Id] = user
+	s.lock.Unlock()
+}
+
+// AddFile serves data as a downloadable attachment, returning an Attachment pointing at it.
+func (s *Server) AddFile(id string, filename string, data []byte) types.Attachment {
+	s.lock.Lock()
+	s.files[filename] = data
+	s.lock.Unlock()
+	return types.Attachment{
+		Id:       id,
+		Filename: filename,
+		Url:      fmt.Sprintf("%s/files/%s", s.server.URL, filename),
+		Size:     len(data),
+	}
+}
+
+// Requests made so far, in order.
+func (s *Server) Requests() []Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Request{}, s.requests...)
+}
+
+// Global commands most recently registered through the API.
+func (s *Server) Commands() []types.ApplicationCommand {
+	return s.GuildCommands("")
+}
+
+// Commands most recently registered in a guild through the API.
+func (s *Server) GuildCommands(guildId string) []types.ApplicationCommand {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]types.ApplicationCommand{}, s.commands[guildId]...)
+}
+
+// WaitForResponse waits for the first request made with an interaction token, such as the callback.
+func (s *Server) WaitForResponse(token string, timeout time.Duration) (Request, error) {
+	return s.WaitForRequest(func(request Request) bool { return request.Token == token }, timeout)
+}
+
+// WaitForRequest waits for the first request matching, including ones already made.
+func (s *Server) WaitForRequest(matches func(Request) bool, timeout time.Duration) (Request, error) {
+	timedOut := false
+	timer := time.AfterFunc(timeout, func() {
+		s.lock.Lock()
+		timedOut = true
+		s.lock.Unlock()
+		s.changed.Broadcast()
+	})
+	defer timer.Stop()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for {
+		for _, request := range s.requests {
+			if matches(request) {
+				return request, nil
+			}
+		}
+		if timedOut {
+			return Request{}, fmt.Errorf("no matching request after %s", timeout)
+		}
+		s.changed.Wait()
+	}
+}
+
+func (s *Server) record(request Request) {
+	s.lock.Lock()
+	s.requests = append(s.requests, request)
+	s.lock.Unlock()
+	s.changed.Broadcast()
+}
+
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	data, ok := s.files[strings.TrimPrefix(r.URL.Path, "/files/")]
+	s.lock.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Write(data)
+}
+
+func (s *Server) handleApi(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v10")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	request, err := readRequest(r, path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, 50035, err.Error())
+		return
+	}
+
+	switch {
+	case r.Method == "GET" && path == "/gateway":
+		writeJson(w, map[string]string{"url": s.GatewayUrl()})
+		return
+	case r.Method == "POST" && len(segments) == 4 && segments[0] == "interactions" && segments[3] == "callback":
+		request.Token = segments[2]
+		s.record(request)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case len(segments) >= 3 && segments[0] == "webhooks":
+		// Followups and edits to the original response.
+		request.Token = segments[2]
+		s.record(request)
+		if r.Method == "DELETE" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		var message types.Message
+		json.Unmarshal(request.Payload, &message)
+		message.Id = fmt.Sprint(time.Now().UnixNano())
+		writeJson(w, message)
+		return
+	case r.Method == "GET" && len(segments) == 2 && segments[0] == "users":
+		s.record(request)
+		s.lock.Lock()
+		user, ok := s.users[segments[1]]
+		s.lock.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, 10013, "Unknown User")
+			return
+		}
+		writeJson(w, user)
+		return
+	case len(segments) == 3 && segments[0] == "applications" && segments[2] == "commands":
+		s.record(request)
+		s.handleCommands(w, r.Method, "", request.Payload)
+		return
+	case len(segments) == 5 && segments[0] == "applications" && segments[2] == "guilds" && segments[4] == "commands":
+		s.record(request)
+		s.handleCommands(w, r.Method, segments[3], request.Payload)
+		return
+	}
+
+	s.record(request)
+	writeError(w, http.StatusNotFound, 0, fmt.Sprintf("404: Not Found (%s %s)", r.Method, path))
+}
+
+func (s *Server) handleCommands(w http.ResponseWriter, method string, guildId string, payload json.RawMessage) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch method {
+	case "GET":
+		writeJson(w, s.commands[guildId])
+	case "PUT":
+		var commands []types.ApplicationCommand
+		json.Unmarshal(payload, &commands)
+		for i := range commands {
+			commands[i].GuildId = guildId
+		}
+		s.commands[guildId] = commands
+		writeJson(w, commands)
+	case "POST":
+		var command types.ApplicationCommand
+		json.Unmarshal(payload, &command)
+		command.GuildId = guildId
+		replaced := false
+		for i, existing := range s.commands[guildId] {
+			if existing.Name == command.Name && existing.Type == command.Type {
+				s.commands[guildId][i] = command
+				replaced = true
+			}
+		}
+		if !replaced {
+			s.commands[guildId] = append(s.commands[guildId], command)
+		}
+		writeJson(w, command)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Read a JSON or multipart request body.
+func readRequest(r *http.Request, path string) (Request, error) {
+	request := Request{Method: r.Method, Path: path, Files: make(map[string][]byte)}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		err := r.ParseMultipartForm(32 << 20)
+		if err != nil {
+			return request, err
+		}
+		if payload, ok := r.MultipartForm.Value["payload_json"]; ok && len(payload) > 0 {
+			request.Payload = json.RawMessage(payload[0])
+		}
+		for _, fileHeaders := range r.MultipartForm.File {
+			for _, fileHeader := range fileHeaders {
+				file, err := fileHeader.Open()
+				if err != nil {
+					return request, err
+				}
+				data, err := io.ReadAll(file)
+				file.Close()
+				if err != nil {
+					return request, err
+				}
+				request.Files[fileHeader.Filename] = data
+			}
+		}
+		return request, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return request, err
+	}
+	if len(body) > 0 {
+		request.Payload = json.RawMessage(body)
+	}
+	return request, nil
+}
+
+func writeJson(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		log.Printf("fakediscord: failed to write response: %s", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"code": code, "message": message})
+}
+
+var upgrader = websocket.Upgrader{}
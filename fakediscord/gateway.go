@@ -0,0 +1,197 @@
+package fakediscord
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+// Heartbeat interval sent in HELLO, in milliseconds.
+const HeartbeatInterval = 45000
+
+type gatewayState struct {
+	conn      *websocket.Conn
+	writeLock sync.Mutex
+	// Whether the current connection has identified or resumed.
+	ready    bool
+	sequence int
+	// Known session IDs, for RESUME.
+	sessions        map[string]int
+	identifies      int
+	resumes         int
+	presenceUpdates []types.PresenceUpdate
+}
+
+type gatewayMessage struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d"`
+	S  *int            `json:"s,omitempty"`
+	T  *string         `json:"t,omitempty"`
+}
+
+func (g *gatewayState) closeConnection() {
+	g.writeLock.Lock()
+	defer g.writeLock.Unlock()
+	if g.conn != nil {
+		g.conn.Close()
+	}
+}
+
+// Number of IDENTIFY and RESUME messages received.
+func (s *Server) SessionStarts() (int, int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.gateway.identifies, s.gateway.resumes
+}
+
+// Presence updates (op 3) received.
+func (s *Server) PresenceUpdates() []types.PresenceUpdate {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]types.PresenceUpdate{}, s.gateway.presenceUpdates...)
+}
+
+// WaitForReady waits until the bot has identified or resumed on its current connection.
+func (s *Server) WaitForReady(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		s.lock.Lock()
+		ready := s.gateway.ready
+		s.lock.Unlock()
+		if ready {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("gateway not ready after %s", timeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// SendInteraction dispatches INTERACTION_CREATE to the connected bot, as an application command unless Type is set.
+func (s *Server) SendInteraction(details types.InteractionCreateDetails) error {
+	if details.Type == 0 {
+		details.Type = 2 // Application command
+	}
+	return s.Dispatch("INTERACTION_CREATE", details)
+}
+
+// Dispatch sends an event (op 0) to the connected bot.
+func (s *Server) Dispatch(event string, data any) error {
+	dat, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	s.gateway.sequence++
+	sequence := s.gateway.sequence
+	s.lock.Unlock()
+
+	return s.writeGateway(gatewayMessage{Op: 0, D: dat, S: &sequence, T: &event})
+}
+
+// RequestReconnect asks the bot to reconnect and resume (op 7).
+func (s *Server) RequestReconnect() error {
+	return s.writeGateway(gatewayMessage{Op: 7, D: json.RawMessage("null")})
+}
+
+func (s *Server) writeGateway(message gatewayMessage) error {
+	s.gateway.writeLock.Lock()
+	defer s.gateway.writeLock.Unlock()
+	if s.gateway.conn == nil {
+		return fmt.Errorf("no gateway connection")
+	}
+	return s.gateway.conn.WriteJSON(message)
+}
+
+func (s *Server) handleGateway(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("fakediscord: upgrade failed: %s", err)
+		return
+	}
+	defer conn.Close()
+
+	s.gateway.writeLock.Lock()
+	s.gateway.conn = conn
+	s.gateway.writeLock.Unlock()
+	s.lock.Lock()
+	s.gateway.ready = false
+	s.lock.Unlock()
+
+	hello, _ := json.Marshal(map[string]int{"heartbeat_interval": HeartbeatInterval})
+	s.writeGateway(gatewayMessage{Op: 10, D: hello})
+
+	for {
+		var message gatewayMessage
+		err := conn.ReadJSON(&message)
+		if err != nil {
+			return
+		}
+
+		switch message.Op {
+		case 1: // Heartbeat
+			s.writeGateway(gatewayMessage{Op: 11})
+		case 2: // Identify
+			s.identify()
+		case 3: // Presence update
+			var presence types.PresenceUpdate
+			json.Unmarshal(message.D, &presence)
+			s.lock.Lock()
+			s.gateway.presenceUpdates = append(s.gateway.presenceUpdates, presence)
+			s.lock.Unlock()
+		case 6: // Resume
+			var resume struct {
+				SessionId string `json:"session_id"`
+			}
+			json.Unmarshal(message.D, &resume)
+			s.resume(resume.SessionId)
+		}
+	}
+}
+
+func (s *Server) identify() {
+	s.lock.Lock()
+	s.gateway.identifies++
+	sessionId := fmt.Sprintf("session-%d", s.gateway.identifies)
+	s.gateway.sessions[sessionId] = 0
+	s.lock.Unlock()
+
+	s.Dispatch("READY", types.ReadyEvent{
+		Version:          10,
+		User:             types.UserData{Id: "1", Username: "haplessbot", Discriminator: "0000"},
+		Guilds:           []types.UnavailableGuild{},
+		SessionId:        sessionId,
+		ResumeGatewayUrl: s.GatewayUrl(),
+	})
+	s.setReady()
+}
+
+func (s *Server) resume(sessionId string) {
+	s.lock.Lock()
+	_, known := s.gateway.sessions[sessionId]
+	if known {
+		s.gateway.resumes++
+	}
+	s.lock.Unlock()
+
+	if !known {
+		s.writeGateway(gatewayMessage{Op: 9, D: json.RawMessage("false")})
+		return
+	}
+	s.Dispatch("RESUMED", struct{}{})
+	s.setReady()
+}
+
+func (s *Server) setReady() {
+	s.lock.Lock()
+	s.gateway.ready = true
+	s.lock.Unlock()
+}
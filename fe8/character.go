@@ -8,6 +8,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 type Characters map[string]Character
@@ -62,8 +63,14 @@ type Character struct {
 
 var characters Characters
 
-func init() {
-	populateData()
+var loadData sync.Once
+
+// Read the data files on first use rather than on import, so packages using this one can be tested without them.
+func ensureLoaded() {
+	loadData.Do(func() {
+		populateData()
+		populateClasses()
+	})
 }
 
 func normalizeName(unnormalizedName string) string {
@@ -247,6 +254,7 @@ type CharacterImage struct {
 }
 
 func GetCharacterData(characterName string) (*CharacterResponse, *string) {
+	ensureLoaded()
 	normalizedName := normalizeName(characterName)
 	character, ok := characters[normalizedName]
 	if !ok {
@@ -301,7 +309,41 @@ Affinity: %s
 	return &result, nil
 }
 
+// Levels and promotions a character can reach, e.g. to offer average stat lookups for.
+type CharacterProgression struct {
+	Name      string
+	BaseLevel int
+	// Highest level in the starting class, lower for trainees.
+	MaxLevel   int
+	Promotions []string
+}
+
+func GetCharacterProgression(characterName string) (*CharacterProgression, *string) {
+	ensureLoaded()
+	character, ok := characters[normalizeName(characterName)]
+	if !ok {
+		err := fmt.Sprintf("Unknown character: %s", characterName)
+		return nil, &err
+	}
+
+	promotions, err := GetPromotions(character.Stats.Class, character.Meta.ClassDiscriminator, character.Meta.StartsFullyPromoted)
+	if err != nil {
+		return nil, err
+	}
+	maxLevel := 20
+	if character.Meta.StartsTrainee {
+		maxLevel = 10
+	}
+	return &CharacterProgression{
+		Name:       character.Name,
+		BaseLevel:  character.Stats.Level,
+		MaxLevel:   maxLevel,
+		Promotions: *promotions,
+	}, nil
+}
+
 func GetAverageStats(characterName string, level int, promotion *string, promotionLevel *int, secondPromotion *string, secondPromotionLevel *int) (*CharacterResponse, *string) {
+	ensureLoaded()
 	normalizedName := normalizeName(characterName)
 	character, ok := characters[normalizedName]
 	if !ok {
@@ -41,7 +41,7 @@ type Class struct {
 
 var classes Classes
 
-func init() {
+func populateClasses() {
 	maxStatsData := readFile("fe8/data/maxstats.tsv")
 	promotionsData := readFile("fe8/data/promotions.tsv")
 
@@ -132,6 +132,7 @@ func addMaxStats(data [][]string) {
 }
 
 func GetClass(className string, classDiscriminator string, fullyPromoted bool) Class {
+	ensureLoaded()
 	// Class without discriminator
 	result, exists := classes[normalizeName(className)]
 	if exists && result.MaxStats != nil {
@@ -159,6 +160,7 @@ func GetClass(className string, classDiscriminator string, fullyPromoted bool) C
 }
 
 func GetPromotions(className string, classDiscriminator string, fullyPromoted bool) (*[]string, *string) {
+	ensureLoaded()
 	class, exists := classes[normalizeName(className)]
 	if !exists || class.Promotions == nil {
 		class, exists = classes[normalizeName(fmt.Sprintf("%s (%s)", className, classDiscriminator))]
@@ -181,6 +183,7 @@ func GetPromotions(className string, classDiscriminator string, fullyPromoted bo
 }
 
 func GetPromotion(startingClass string, promotionClass string, classDiscriminator string) (*Promotion, *string) {
+	ensureLoaded()
 	fullStartingClass := fmt.Sprintf("%s (%s)", startingClass, classDiscriminator)
 	fullPromotionClass := fmt.Sprintf("%s (%s)", promotionClass, classDiscriminator)
 	class, exists := classes[normalizeName(startingClass)]
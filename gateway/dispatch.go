@@ -0,0 +1,160 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/haplesspanda/haplessbot/rest"
+	"github.com/haplesspanda/haplessbot/types"
+	"github.com/haplesspanda/haplessbot/worker"
+)
+
+// Event names sent with op 0 dispatches.
+const (
+	EventReady                 = "READY"
+	EventResumed               = "RESUMED"
+	EventInteractionCreate     = "INTERACTION_CREATE"
+	EventMessageCreate         = "MESSAGE_CREATE"
+	EventMessageDelete         = "MESSAGE_DELETE"
+	EventGuildCreate           = "GUILD_CREATE"
+	EventGuildMemberAdd        = "GUILD_MEMBER_ADD"
+	EventGuildMemberUpdate     = "GUILD_MEMBER_UPDATE"
+	EventGuildMemberRemove     = "GUILD_MEMBER_REMOVE"
+	EventMessageReactionAdd    = "MESSAGE_REACTION_ADD"
+	EventMessageReactionRemove = "MESSAGE_REACTION_REMOVE"
+)
+
+type eventHandler func(data json.RawMessage)
+
+var handlers = make(map[string][]eventHandler)
+var handlersLock = sync.RWMutex{}
+
+// Set on shutdown to stop accepting new interactions.
+var draining atomic.Bool
+
+// Interaction handlers run here instead of the read loop, so slow commands don't hold up other events.
+var interactionPool *worker.Pool
+
+// How dropped interactions are answered, see Options.BusyResponse.
+var busyClient *rest.Client
+var busyResponse func(types.InteractionCreateDetails) types.InteractionCallbackMessage
+
+// How long to try telling a user their interaction was dropped.
+var busyReplyTimeout = 5 * time.Second
+
+// AddHandler registers a handler called with the decoded payload of every event with the given name.
+// Handlers run in the connection's read loop, in registration order.
+func AddHandler[T any](event string, handler func(T)) {
+	handlersLock.Lock()
+	defer handlersLock.Unlock()
+	handlers[event] = append(handlers[event], func(data json.RawMessage) {
+		var parsed T
+		err := json.Unmarshal(data, &parsed)
+		if err != nil {
+			log.Printf("Failed to parse %s event: %s", event, err)
+			return
+		}
+		handler(parsed)
+	})
+}
+
+func OnReady(handler func(types.ReadyEvent)) {
+	AddHandler(EventReady, handler)
+}
+
+// OnInteractionCreate registers a handler run on the interaction worker pool, with a context that times out
+// per StartConnection's options.
+func OnInteractionCreate(handler func(context.Context, types.InteractionCreateDetails)) {
+	AddHandler(EventInteractionCreate, func(details types.InteractionCreateDetails) {
+		submitted := interactionPool.Submit(fmt.Sprintf("interaction %s", details.Id), func(ctx context.Context) {
+			handler(ctx, details)
+		})
+		if !submitted {
+			log.Printf("Interaction queue full (depth %d), dropping interaction %s", interactionPool.QueueDepth(), details.Id)
+			rejectInteraction(details)
+		}
+	})
+}
+
+func OnMessageCreate(handler func(types.Message)) {
+	AddHandler(EventMessageCreate, handler)
+}
+
+func OnMessageDelete(handler func(types.MessageDeleteEvent)) {
+	AddHandler(EventMessageDelete, handler)
+}
+
+func OnGuildCreate(handler func(types.Guild)) {
+	AddHandler(EventGuildCreate, handler)
+}
+
+func OnGuildMemberAdd(handler func(types.GuildMemberAddEvent)) {
+	AddHandler(EventGuildMemberAdd, handler)
+}
+
+func OnGuildMemberUpdate(handler func(types.GuildMemberUpdateEvent)) {
+	AddHandler(EventGuildMemberUpdate, handler)
+}
+
+func OnGuildMemberRemove(handler func(types.GuildMemberRemoveEvent)) {
+	AddHandler(EventGuildMemberRemove, handler)
+}
+
+func OnMessageReactionAdd(handler func(types.MessageReactionAddEvent)) {
+	AddHandler(EventMessageReactionAdd, handler)
+}
+
+func OnMessageReactionRemove(handler func(types.MessageReactionRemoveEvent)) {
+	AddHandler(EventMessageReactionRemove, handler)
+}
+
+func dispatch(event string, data json.RawMessage) {
+	handlersLock.RLock()
+	eventHandlers := handlers[event]
+	handlersLock.RUnlock()
+
+	if len(eventHandlers) == 0 {
+		log.Printf("No handlers for event %s, ignoring", event)
+		return
+	}
+	if event == EventInteractionCreate && draining.Load() {
+		log.Printf("Shutting down, dropping interaction")
+		var details types.InteractionCreateDetails
+		if json.Unmarshal(data, &details) == nil {
+			rejectInteraction(details)
+		}
+		return
+	}
+
+	for _, handler := range eventHandlers {
+		handler(data)
+	}
+}
+
+// Tell the user their interaction won't be handled, instead of letting it time out. Runs in the background so the
+// read loop isn't held up.
+func rejectInteraction(details types.InteractionCreateDetails) {
+	if busyClient == nil || busyResponse == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), busyReplyTimeout)
+		defer cancel()
+		err := busyClient.CreateInteractionResponse(ctx, details.Id, details.Token, busyResponse(details))
+		if err != nil {
+			log.Printf("Failed to send busy reply for interaction %s: %s", details.Id, err)
+		}
+	}()
+}
+
+// Stop accepting interactions and wait for queued and running ones. Returns false if they did not finish in time.
+// Other events are handled in the read loop, so they're done once it stops.
+func drainHandlers(timeout time.Duration) bool {
+	draining.Store(true)
+	return interactionPool.Shutdown(timeout)
+}
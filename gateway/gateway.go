@@ -1,51 +1,97 @@
 package gateway
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"math/rand"
-	"net/http"
-	"os"
-	"os/signal"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
-	"github.com/haplesspanda/haplessbot/commands"
 	"github.com/haplesspanda/haplessbot/constants"
+	"github.com/haplesspanda/haplessbot/rest"
 	"github.com/haplesspanda/haplessbot/types"
+	"github.com/haplesspanda/haplessbot/worker"
 )
 
-var heartbeatTimer *time.Timer
 var lastSequence *int
 var sequenceLock = sync.Mutex{}
 var writeLock = sync.Mutex{}
-var sessionId *string
+var intents int
+var sessionFile string
+
+type Options struct {
+	// Used to look up the gateway URL, and to tell users when an interaction can't be handled.
+	Client *rest.Client
+	// Gateway to connect to instead of the one Discord reports, e.g. a fake for tests.
+	GatewayUrl string
+	// Bitfield of gateway intents, see ParseIntents.
+	Intents  int
+	Presence PresenceOptions
+	// Where to keep the session on shutdown so the next start can RESUME. Disabled if empty.
+	SessionFile string
+	// How long to wait for in-flight handlers on shutdown.
+	ShutdownTimeout time.Duration
+	// Goroutines running interaction handlers, and how many interactions may wait for one.
+	Workers            int
+	WorkerQueueDepth   int
+	InteractionTimeout time.Duration
+	// Reply to interactions dropped because the queue is full or the bot is shutting down. They time out without a
+	// reply if nil.
+	BusyResponse func(types.InteractionCreateDetails) types.InteractionCallbackMessage
+}
 
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
-func StartConnection() {
-	gatewayUrl := getGatewayUrl(true)
+// StartConnection connects to the gateway and handles events until ctx is cancelled, then shuts down cleanly.
+func StartConnection(ctx context.Context, options Options) {
+	intents = options.Intents
+	interactionPool = worker.NewPool(options.Workers, options.WorkerQueueDepth, options.InteractionTimeout)
+	busyClient = options.Client
+	busyResponse = options.BusyResponse
+	draining.Store(false)
+	presenceOptions = options.Presence
+	if presenceOptions.RotateInterval > 0 && len(presenceOptions.Activities) > 1 {
+		go presenceScheduler()
+	}
+	gatewayUrl := getGatewayUrl(options.Client, options.GatewayUrl)
 
-	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt)
+	sessionFile = options.SessionFile
+	resumeSaved := false
+	if sessionFile != "" {
+		saved, err := loadSession(sessionFile)
+		if err != nil {
+			log.Printf("Failed to load saved session, identifying instead: %s", err)
+		} else if saved != nil {
+			log.Printf("Loaded saved session %s at sequence %v", saved.SessionId, saved.Sequence)
+			setSession(saved.SessionId, saved.ResumeGatewayUrl)
+			setSequence(saved.Sequence)
+			resumeSaved = true
+		}
+	}
 
-	connect(gatewayUrl, interrupt)
+	connect(ctx, gatewayUrl, resumeSaved, options.ShutdownTimeout)
 }
 
-func connect(url string, interrupt chan os.Signal) {
-	reconnect := false
-
+func connect(ctx context.Context, gatewayUrl string, reconnect bool, shutdownTimeout time.Duration) {
 	for {
+		url := gatewayUrl
+		if _, resumeUrl := getSession(); reconnect && resumeUrl != "" {
+			url = resumeUrl
+		}
+
 		log.Printf("Connecting to %s, reconnect=%t", url, reconnect)
 		c, _, err := websocket.DefaultDialer.Dial(url, nil)
 		if err != nil {
 			log.Fatalf("Dial error: %s", err)
 		}
+		queue := newSendQueue(c)
+		go queue.run()
+		setCurrentQueue(queue)
 
 		reconnectChannel := make(chan struct{})
 
@@ -61,10 +107,10 @@ func connect(url string, interrupt chan os.Signal) {
 				log.Printf("received raw: %s", message)
 
 				type GenericMessage struct {
-					Op int     `json:"op"`
-					D  any     `json:"d"`
-					S  *int    `json:"s"`
-					T  *string `json:"t"`
+					Op int             `json:"op"`
+					D  json.RawMessage `json:"d"`
+					S  *int            `json:"s"`
+					T  *string         `json:"t"`
 				}
 				var parsedResponse GenericMessage
 				json.Unmarshal(message, &parsedResponse)
@@ -73,39 +119,21 @@ func connect(url string, interrupt chan os.Signal) {
 
 				switch parsedResponse.Op {
 				case 0: // Event dispatch (everything else)
-					type InteractionCreateMessage struct {
-						Opt int                            `json:"op"`
-						D   types.InteractionCreateDetails `json:"d"`
-						T   string                         `json:"t"`
-						S   int                            `json:"s"`
+					if parsedResponse.T == nil {
+						log.Printf("Dispatch without event name, ignoring")
+						break
 					}
-
-					var parsedMessage InteractionCreateMessage
-					json.Unmarshal(message, &parsedMessage)
-
-					log.Printf("Parsed event dispatch message as %v", parsedMessage)
-
-					if parsedMessage.T == "READY" {
-						type ReadyMessageDetails struct {
-							SessionId string `json:"session_id"`
-						}
-
-						type ReadyMessage struct {
-							Op int                 `json:"op"`
-							D  ReadyMessageDetails `json:"d"`
-							T  string              `json:"t"`
-							S  string              `json:"s"`
-						}
-
-						var readyMessage ReadyMessage
-						json.Unmarshal(message, &readyMessage)
+					if *parsedResponse.T == EventReady {
+						var readyMessage types.ReadyEvent
+						json.Unmarshal(parsedResponse.D, &readyMessage)
 
 						log.Printf("Parsed ready message as %v", readyMessage)
-						sessionId = &readyMessage.D.SessionId
-					} else if parsedMessage.T == "INTERACTION_CREATE" {
-						commands.RunInteractionCallback(parsedMessage.D)
+						setSession(readyMessage.SessionId, readyMessage.ResumeGatewayUrl)
 					}
-					setSequence(&parsedMessage.S)
+					if parsedResponse.S != nil {
+						setSequence(parsedResponse.S)
+					}
+					dispatch(*parsedResponse.T, parsedResponse.D)
 				case 1: // Heartbeat
 					type HeartbeatMessageRecv struct {
 						Op int `json:"op"`
@@ -116,10 +144,22 @@ func connect(url string, interrupt chan os.Signal) {
 
 					log.Printf("Parsed heartbeat message as %v", parsedHeartbeatMessage)
 					// Immediate response.
-					writeHeartbeat(c)
+					writeHeartbeat(queue)
 				case 7: // Reconnect
 					close(reconnectChannel)
 					return
+				case 9: // Invalid session
+					var resumable bool
+					json.Unmarshal(parsedResponse.D, &resumable)
+
+					log.Printf("Invalid session, resumable=%t", resumable)
+					if !resumable {
+						clearSession()
+						// Discord asks for a random 1-5 second wait before identifying again.
+						time.Sleep(time.Duration(1000+rand.Intn(4000)) * time.Millisecond)
+					}
+					close(reconnectChannel)
+					return
 				case 10: // Hello
 					type HelloMessage struct {
 						Op int `json:"op"`
@@ -132,11 +172,11 @@ func connect(url string, interrupt chan os.Signal) {
 
 					log.Printf("Parsed hello response as: %v", parsedHelloMessage)
 
-					go heartbeatScheduler(c, parsedHelloMessage.D.HeartbeatInterval)
+					go heartbeatScheduler(queue, parsedHelloMessage.D.HeartbeatInterval)
 					if reconnect {
-						resume(c)
+						resume(queue)
 					} else {
-						identify(c)
+						identify(queue)
 					}
 				case 11: // Heartbeat ack
 					type HeartbeatAckMessage struct {
@@ -154,27 +194,54 @@ func connect(url string, interrupt chan os.Signal) {
 
 		select {
 		case <-reconnectChannel:
+			setCurrentQueue(nil)
+			queue.close()
 			c.Close()
-			reconnect = true
+			id, _ := getSession()
+			reconnect = id != nil
 			continue
-		case <-interrupt:
-			fmt.Println("interrupt")
+		case <-ctx.Done():
+			fmt.Println("Shutting down")
+			log.Printf("Shutting down, waiting up to %s for in-flight handlers", shutdownTimeout)
+			if !drainHandlers(shutdownTimeout) {
+				log.Printf("Handlers still running after %s, closing anyway", shutdownTimeout)
+			}
+
+			setCurrentQueue(nil)
+			queue.close()
 			// Cleanly close the connection by sending a close message and then
 			// waiting (with timeout) for the server to close the connection.
+			// Normal closure invalidates the session, so use another code if it should be resumed later.
+			closeCode := websocket.CloseNormalClosure
+			if sessionFile != "" {
+				closeCode = websocket.CloseServiceRestart
+			}
 			writeLock.Lock()
-			err := c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			err := c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, ""))
 			writeLock.Unlock()
 			if err != nil {
 				log.Printf("write close error: %s", err)
-				return
+			} else {
+				select {
+				case <-reconnectChannel:
+				case <-time.After(time.Second):
+				}
+			}
+			c.Close()
+
+			if sessionFile != "" {
+				err = saveSession(sessionFile)
+				if err != nil {
+					log.Printf("Failed to save session: %s", err)
+				}
 			}
-			<-time.After(time.Second)
+			log.Printf("Gateway connection closed")
 			return
 		}
 	}
 }
 
-func identify(conn *websocket.Conn) {
+func identify(queue *sendQueue) {
 	type Properties struct {
 		Os      string `json:"os"`
 		Browser string `json:"browser"`
@@ -182,14 +249,15 @@ func identify(conn *websocket.Conn) {
 	}
 
 	type IdentifyMessageDetails struct {
-		Token      string     `json:"token"`
-		Properties Properties `json:"properties"`
+		Token      string               `json:"token"`
+		Properties Properties           `json:"properties"`
+		Intents    int                  `json:"intents"`
+		Presence   types.PresenceUpdate `json:"presence"`
 	}
 
 	type IdentifyMessage struct {
-		Op      int                    `json:"op"`
-		D       IdentifyMessageDetails `json:"d"`
-		Intents int                    `json:"intents"`
+		Op int                    `json:"op"`
+		D  IdentifyMessageDetails `json:"d"`
 	}
 
 	identifyMessage := new(IdentifyMessage)
@@ -201,12 +269,13 @@ func identify(conn *websocket.Conn) {
 			Browser: "haplessbot",
 			Device:  "haplessbot",
 		},
+		Intents:  intents,
+		Presence: currentPresence(),
 	}
-	identifyMessage.Intents = 0
-	write(conn, identifyMessage)
+	queue.send(identifyMessage)
 }
 
-func resume(conn *websocket.Conn) {
+func resume(queue *sendQueue) {
 	type ResumeMessageDetails struct {
 		Token     string  `json:"token"`
 		SessionId *string `json:"session_id"`
@@ -219,6 +288,7 @@ func resume(conn *websocket.Conn) {
 	}
 
 	sequence := getSequence()
+	sessionId, _ := getSession()
 
 	resumeMessage := new(ResumeMessage)
 	resumeMessage.Op = 6
@@ -227,28 +297,30 @@ func resume(conn *websocket.Conn) {
 		SessionId: sessionId,
 		Sequence:  sequence,
 	}
-	write(conn, resumeMessage)
+	queue.send(resumeMessage)
 }
 
-func heartbeatScheduler(conn *websocket.Conn, intervalMillis int) {
+func heartbeatScheduler(queue *sendQueue, intervalMillis int) {
 	// Add jitter to first heartbeat.
 	scheduleInterval := float64(intervalMillis) * rand.Float64()
-	scheduleHeartbeat(conn, int(scheduleInterval))
-	// Rest of heartbeats stay on the schedule.
-	for {
-		scheduleHeartbeat(conn, intervalMillis)
+	// Rest of heartbeats stay on the schedule, until the connection goes away.
+	for scheduleHeartbeat(queue, int(scheduleInterval)) {
+		scheduleInterval = float64(intervalMillis)
 	}
 }
 
-func scheduleHeartbeat(conn *websocket.Conn, intervalMillis int) {
-	if heartbeatTimer != nil {
-		heartbeatTimer.Stop()
-	}
-	heartbeatTimer = time.NewTimer(time.Duration(intervalMillis) * time.Millisecond)
-	defer heartbeatTimer.Stop()
+// Returns false once the connection is closed.
+func scheduleHeartbeat(queue *sendQueue, intervalMillis int) bool {
+	timer := time.NewTimer(time.Duration(intervalMillis) * time.Millisecond)
+	defer timer.Stop()
 	log.Printf("Scheduling heartbeat in %d millis", intervalMillis)
-	<-heartbeatTimer.C
-	writeHeartbeat(conn)
+	select {
+	case <-queue.done:
+		return false
+	case <-timer.C:
+		writeHeartbeat(queue)
+		return true
+	}
 }
 
 func setSequence(sequence *int) {
@@ -263,7 +335,7 @@ func getSequence() *int {
 	return lastSequence
 }
 
-func writeHeartbeat(conn *websocket.Conn) {
+func writeHeartbeat(queue *sendQueue) {
 	sequence := getSequence()
 	type heartbeat struct {
 		Op int  `json:"op"`
@@ -272,10 +344,10 @@ func writeHeartbeat(conn *websocket.Conn) {
 	heartbeatJson := new(heartbeat)
 	heartbeatJson.Op = 1
 	heartbeatJson.D = sequence
-	write(conn, heartbeatJson)
+	queue.sendPriority(heartbeatJson)
 }
 
-func write(conn *websocket.Conn, jsonMessage any) {
+func write(conn *websocket.Conn, jsonMessage any) error {
 	formatJson, err := json.MarshalIndent(jsonMessage, "", "    ")
 	if err != nil {
 		panic(err)
@@ -286,32 +358,23 @@ func write(conn *websocket.Conn, jsonMessage any) {
 	writeLock.Unlock()
 	if err != nil {
 		log.Printf("write err: %s", err)
-		return
+		return err
 	}
+	return nil
 }
 
-func getGatewayUrl(useCache bool) string {
-	if !useCache {
-		return getCachedUrl()
+func getGatewayUrl(client *rest.Client, override string) string {
+	if override != "" {
+		return override
 	}
 
 	// Lookup the latest.
-	response, err := http.Get("https://discord.com/api/v10/gateway")
+	url, err := client.GetGateway(context.Background())
 	if err != nil {
-		panic(err)
-	}
-	defer response.Body.Close()
-
-	var urlJson struct{ Url string }
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		panic(err)
-	}
-	err = json.Unmarshal(body, &urlJson)
-	if err != nil {
-		panic(err)
+		log.Printf("Gateway lookup failed, falling back to default: %s", err)
+		return getCachedUrl()
 	}
-	return urlJson.Url
+	return url
 }
 
 func getCachedUrl() string {
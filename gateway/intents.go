@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Gateway intents, see https://discord.com/developers/docs/topics/gateway#gateway-intents
+const (
+	IntentGuilds                      = 1 << 0
+	IntentGuildMembers                = 1 << 1 // Privileged
+	IntentGuildModeration             = 1 << 2
+	IntentGuildEmojisAndStickers      = 1 << 3
+	IntentGuildIntegrations           = 1 << 4
+	IntentGuildWebhooks               = 1 << 5
+	IntentGuildInvites                = 1 << 6
+	IntentGuildVoiceStates            = 1 << 7
+	IntentGuildPresences              = 1 << 8 // Privileged
+	IntentGuildMessages               = 1 << 9
+	IntentGuildMessageReactions       = 1 << 10
+	IntentGuildMessageTyping          = 1 << 11
+	IntentDirectMessages              = 1 << 12
+	IntentDirectMessageReactions      = 1 << 13
+	IntentDirectMessageTyping         = 1 << 14
+	IntentMessageContent              = 1 << 15 // Privileged
+	IntentGuildScheduledEvents        = 1 << 16
+	IntentAutoModerationConfiguration = 1 << 20
+	IntentAutoModerationExecution     = 1 << 21
+)
+
+var intentNames = map[string]int{
+	"guilds":                        IntentGuilds,
+	"guild_members":                 IntentGuildMembers,
+	"guild_moderation":              IntentGuildModeration,
+	"guild_emojis_and_stickers":     IntentGuildEmojisAndStickers,
+	"guild_integrations":            IntentGuildIntegrations,
+	"guild_webhooks":                IntentGuildWebhooks,
+	"guild_invites":                 IntentGuildInvites,
+	"guild_voice_states":            IntentGuildVoiceStates,
+	"guild_presences":               IntentGuildPresences,
+	"guild_messages":                IntentGuildMessages,
+	"guild_message_reactions":       IntentGuildMessageReactions,
+	"guild_message_typing":          IntentGuildMessageTyping,
+	"direct_messages":               IntentDirectMessages,
+	"direct_message_reactions":      IntentDirectMessageReactions,
+	"direct_message_typing":         IntentDirectMessageTyping,
+	"message_content":               IntentMessageContent,
+	"guild_scheduled_events":        IntentGuildScheduledEvents,
+	"auto_moderation_configuration": IntentAutoModerationConfiguration,
+	"auto_moderation_execution":     IntentAutoModerationExecution,
+}
+
+// ParseIntents combines intent names (case-insensitive, e.g. "guild_messages") into the bitfield sent in IDENTIFY.
+func ParseIntents(names []string) (int, error) {
+	result := 0
+	for _, name := range names {
+		normalizedName := strings.ToLower(strings.TrimSpace(name))
+		if normalizedName == "" {
+			continue
+		}
+		intent, ok := intentNames[normalizedName]
+		if !ok {
+			return 0, fmt.Errorf("unknown intent: %s", name)
+		}
+		result |= intent
+	}
+	return result, nil
+}
@@ -0,0 +1,30 @@
+package gateway
+
+import "testing"
+
+func TestParseIntents(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		want    int
+		wantErr bool
+	}{
+		{"none", nil, 0, false},
+		{"one", []string{"guilds"}, IntentGuilds, false},
+		{"several", []string{"guilds", "guild_members", "message_content"}, IntentGuilds | IntentGuildMembers | IntentMessageContent, false},
+		{"case and spaces", []string{" Guild_Messages ", "DIRECT_MESSAGES"}, IntentGuildMessages | IntentDirectMessages, false},
+		{"repeated", []string{"guilds", "guilds"}, IntentGuilds, false},
+		{"blank entries skipped", []string{"", " ", "guilds"}, IntentGuilds, false},
+		{"unknown", []string{"guilds", "guild_everything"}, 0, true},
+	}
+	for _, test := range tests {
+		got, err := ParseIntents(test.names)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error %t", test.name, err, test.wantErr)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: got %d, want %d", test.name, got, test.want)
+		}
+	}
+}
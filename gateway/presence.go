@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+type PresenceOptions struct {
+	// One of online, idle, dnd or invisible. Defaults to online.
+	Status string
+	// Activities to rotate through. The first one is sent with IDENTIFY.
+	Activities []types.Activity
+	// How long to show each activity for. Rotation is disabled if zero or there are fewer than two activities.
+	RotateInterval time.Duration
+}
+
+var presenceOptions PresenceOptions
+var presenceIndex int
+var presenceOverride *types.Activity
+var presenceLock = sync.Mutex{}
+
+// UpdatePresence sends a presence update (op 3) on the current connection.
+func UpdatePresence(presence types.PresenceUpdate) error {
+	queue := getCurrentQueue()
+	if queue == nil {
+		return errors.New("no gateway connection")
+	}
+
+	type PresenceUpdateMessage struct {
+		Op int                  `json:"op"`
+		D  types.PresenceUpdate `json:"d"`
+	}
+	return queue.send(PresenceUpdateMessage{Op: 3, D: presence})
+}
+
+// SetCustomActivity shows the given activity instead of the rotating ones until cleared by passing nil.
+func SetCustomActivity(activity *types.Activity) error {
+	presenceLock.Lock()
+	presenceOverride = activity
+	presenceLock.Unlock()
+
+	return UpdatePresence(currentPresence())
+}
+
+func currentPresence() types.PresenceUpdate {
+	presenceLock.Lock()
+	defer presenceLock.Unlock()
+
+	status := presenceOptions.Status
+	if status == "" {
+		status = "online"
+	}
+	activities := []types.Activity{}
+	if presenceOverride != nil {
+		activities = append(activities, *presenceOverride)
+	} else if len(presenceOptions.Activities) > 0 {
+		activities = append(activities, presenceOptions.Activities[presenceIndex])
+	}
+	return types.PresenceUpdate{
+		Activities: activities,
+		Status:     status,
+	}
+}
+
+func presenceScheduler() {
+	ticker := time.NewTicker(presenceOptions.RotateInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		presenceLock.Lock()
+		presenceIndex = (presenceIndex + 1) % len(presenceOptions.Activities)
+		overridden := presenceOverride != nil
+		presenceLock.Unlock()
+
+		if overridden {
+			continue
+		}
+		err := UpdatePresence(currentPresence())
+		if err != nil {
+			log.Printf("Failed to rotate presence: %s", err)
+		}
+	}
+}
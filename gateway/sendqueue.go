@@ -0,0 +1,199 @@
+package gateway
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Discord disconnects clients sending more than 120 events per 60 seconds.
+const sendLimit = 120
+const sendWindow = 60 * time.Second
+
+// Sends only heartbeats may use, so other traffic can never starve them.
+const heartbeatReserve = 5
+
+const sendQueueSize = 128
+
+var errQueueClosed = errors.New("send queue closed")
+var errQueueFull = errors.New("send queue full")
+
+type SendStats struct {
+	// Messages waiting to be sent, excluding heartbeats.
+	QueueDepth int
+	// Heartbeats waiting to be sent.
+	PriorityQueueDepth int
+	// Messages written to the connection.
+	Sent int64
+	// Times a send had to wait for the rate limit.
+	Throttled int64
+}
+
+var sentCount int64
+var throttledCount int64
+
+type outgoingMessage struct {
+	message any
+	result  chan error
+}
+
+// Token bucket rate limited writer for a single connection.
+type sendQueue struct {
+	conn      *websocket.Conn
+	priority  chan outgoingMessage
+	normal    chan outgoingMessage
+	done      chan struct{}
+	closeOnce sync.Once
+
+	tokens     float64
+	lastRefill time.Time
+}
+
+var currentQueue *sendQueue
+var queueLock = sync.Mutex{}
+
+func setCurrentQueue(queue *sendQueue) {
+	queueLock.Lock()
+	currentQueue = queue
+	queueLock.Unlock()
+}
+
+func getCurrentQueue() *sendQueue {
+	queueLock.Lock()
+	defer queueLock.Unlock()
+	return currentQueue
+}
+
+// GetSendStats reports the state of the current connection's send queue and totals across connections.
+func GetSendStats() SendStats {
+	stats := SendStats{
+		Sent:      atomic.LoadInt64(&sentCount),
+		Throttled: atomic.LoadInt64(&throttledCount),
+	}
+	queue := getCurrentQueue()
+	if queue != nil {
+		stats.QueueDepth = len(queue.normal)
+		stats.PriorityQueueDepth = len(queue.priority)
+	}
+	return stats
+}
+
+func newSendQueue(conn *websocket.Conn) *sendQueue {
+	return &sendQueue{
+		conn:       conn,
+		priority:   make(chan outgoingMessage, sendQueueSize),
+		normal:     make(chan outgoingMessage, sendQueueSize),
+		done:       make(chan struct{}),
+		tokens:     sendLimit,
+		lastRefill: time.Now(),
+	}
+}
+
+// Queue a message and wait until it is written.
+func (q *sendQueue) send(message any) error {
+	return q.enqueue(q.normal, message)
+}
+
+// Queue a heartbeat ahead of everything else and wait until it is written.
+func (q *sendQueue) sendPriority(message any) error {
+	return q.enqueue(q.priority, message)
+}
+
+func (q *sendQueue) enqueue(channel chan outgoingMessage, message any) error {
+	outgoing := outgoingMessage{message: message, result: make(chan error, 1)}
+	select {
+	case <-q.done:
+		return errQueueClosed
+	case channel <- outgoing:
+	default:
+		log.Printf("Send queue full, dropping message")
+		return errQueueFull
+	}
+
+	select {
+	case err := <-outgoing.result:
+		return err
+	case <-q.done:
+		return errQueueClosed
+	}
+}
+
+func (q *sendQueue) close() {
+	q.closeOnce.Do(func() {
+		close(q.done)
+	})
+}
+
+func (q *sendQueue) run() {
+	for {
+		// Heartbeats always go first.
+		select {
+		case outgoing := <-q.priority:
+			q.write(outgoing, 0)
+			continue
+		default:
+		}
+
+		select {
+		case <-q.done:
+			return
+		case outgoing := <-q.priority:
+			q.write(outgoing, 0)
+		case outgoing := <-q.normal:
+			q.write(outgoing, heartbeatReserve)
+		}
+	}
+}
+
+// Wait for a token (leaving reserve tokens untouched) and then write the message.
+func (q *sendQueue) write(outgoing outgoingMessage, reserve int) {
+	for !q.takeToken(reserve) {
+		wait := q.untilAvailable(reserve)
+		atomic.AddInt64(&throttledCount, 1)
+		log.Printf("Gateway send rate limited, waiting %s (queued=%d, priority=%d)", wait, len(q.normal), len(q.priority))
+
+		select {
+		case <-q.done:
+			outgoing.result <- errQueueClosed
+			return
+		case heartbeat := <-q.priority:
+			// Heartbeats can use the reserve, so don't make them wait behind us.
+			q.write(heartbeat, 0)
+		case <-time.After(wait):
+		}
+	}
+
+	err := write(q.conn, outgoing.message)
+	if err == nil {
+		atomic.AddInt64(&sentCount, 1)
+	}
+	outgoing.result <- err
+}
+
+func (q *sendQueue) refill() {
+	now := time.Now()
+	elapsed := now.Sub(q.lastRefill)
+	q.lastRefill = now
+	q.tokens += elapsed.Seconds() * sendLimit / sendWindow.Seconds()
+	if q.tokens > sendLimit {
+		q.tokens = sendLimit
+	}
+}
+
+func (q *sendQueue) takeToken(reserve int) bool {
+	q.refill()
+	if q.tokens-1 < float64(reserve) {
+		return false
+	}
+	q.tokens--
+	return true
+}
+
+func (q *sendQueue) untilAvailable(reserve int) time.Duration {
+	missing := float64(reserve) + 1 - q.tokens
+	return time.Duration(missing * float64(sendWindow) / sendLimit)
+}
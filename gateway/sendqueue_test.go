@@ -0,0 +1,146 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestTakeToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		tokens  float64
+		reserve int
+		want    bool
+		left    float64
+	}{
+		{"full", sendLimit, heartbeatReserve, true, sendLimit - 1},
+		{"above reserve", heartbeatReserve + 1, heartbeatReserve, true, heartbeatReserve},
+		{"at reserve", heartbeatReserve, heartbeatReserve, false, heartbeatReserve},
+		{"heartbeat uses reserve", heartbeatReserve, 0, true, heartbeatReserve - 1},
+		{"heartbeat with one left", 1, 0, true, 0},
+		{"empty", 0.5, 0, false, 0.5},
+	}
+	for _, test := range tests {
+		q := &sendQueue{tokens: test.tokens, lastRefill: time.Now()}
+		got := q.takeToken(test.reserve)
+		// Allow for the bit refilled during the test.
+		if got != test.want || q.tokens < test.left || q.tokens > test.left+0.01 {
+			t.Errorf("%s: got %t with %.1f left, want %t with %.1f left", test.name, got, q.tokens, test.want, test.left)
+		}
+	}
+}
+
+func TestRefill(t *testing.T) {
+	tests := []struct {
+		name    string
+		tokens  float64
+		elapsed time.Duration
+		want    float64
+	}{
+		{"half a window", 0, sendWindow / 2, sendLimit / 2},
+		{"capped at the limit", sendLimit - 1, sendWindow, sendLimit},
+	}
+	for _, test := range tests {
+		q := &sendQueue{tokens: test.tokens, lastRefill: time.Now().Add(-test.elapsed)}
+		q.refill()
+		// Allow for the time passing during the test.
+		if q.tokens < test.want-0.1 || q.tokens > test.want+0.1 {
+			t.Errorf("%s: got %.2f tokens, want %.2f", test.name, q.tokens, test.want)
+		}
+	}
+}
+
+func TestUntilAvailable(t *testing.T) {
+	q := &sendQueue{tokens: 0}
+	// Each token takes half a second to come back, and a normal send needs one more than the reserve.
+	if got, want := q.untilAvailable(heartbeatReserve), 3*time.Second; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	q.tokens = 0.5
+	if got, want := q.untilAvailable(0), 250*time.Millisecond; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// Start a websocket server that passes everything it receives to the returned channel.
+func recordingServer(t *testing.T) (*websocket.Conn, <-chan string) {
+	received := make(chan string, 10)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			received <- strings.TrimSpace(string(message))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, received
+}
+
+func TestHeartbeatsSkipThrottledSends(t *testing.T) {
+	conn, received := recordingServer(t)
+	q := newSendQueue(conn)
+	// Only the reserve is left, so normal sends wait while heartbeats go through.
+	q.tokens = heartbeatReserve
+	go q.run()
+
+	normalResult := make(chan error, 1)
+	go func() {
+		normalResult <- q.send("presence")
+	}()
+	// Let the normal send start waiting for a token.
+	time.Sleep(50 * time.Millisecond)
+
+	err := q.sendPriority("heartbeat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case message := <-received:
+		if message != `"heartbeat"` {
+			t.Errorf("got %s first, want the heartbeat", message)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("heartbeat wasn't sent")
+	}
+
+	q.close()
+	select {
+	case err := <-normalResult:
+		if err != errQueueClosed {
+			t.Errorf("got %v for the throttled send, want errQueueClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("throttled send didn't stop when the queue closed")
+	}
+}
+
+func TestSendQueueFull(t *testing.T) {
+	// Nothing runs the queue, so it fills up.
+	q := newSendQueue(nil)
+	for i := 0; i < sendQueueSize; i++ {
+		q.normal <- outgoingMessage{message: i, result: make(chan error, 1)}
+	}
+	if err := q.send("one too many"); err != errQueueFull {
+		t.Errorf("got %v, want errQueueFull", err)
+	}
+	q.close()
+}
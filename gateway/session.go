@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Session details needed to RESUME, persisted across restarts.
+type savedSession struct {
+	SessionId        string `json:"session_id"`
+	Sequence         *int   `json:"seq"`
+	ResumeGatewayUrl string `json:"resume_gateway_url"`
+}
+
+var sessionId *string
+var resumeGatewayUrl string
+var sessionLock = sync.Mutex{}
+
+func setSession(id string, resumeUrl string) {
+	sessionLock.Lock()
+	sessionId = &id
+	resumeGatewayUrl = resumeUrl
+	sessionLock.Unlock()
+}
+
+func getSession() (*string, string) {
+	sessionLock.Lock()
+	defer sessionLock.Unlock()
+	return sessionId, resumeGatewayUrl
+}
+
+func clearSession() {
+	sessionLock.Lock()
+	sessionId = nil
+	resumeGatewayUrl = ""
+	sessionLock.Unlock()
+	setSequence(nil)
+}
+
+func saveSession(filename string) error {
+	id, resumeUrl := getSession()
+	if id == nil {
+		// Nothing to resume, make sure an older session isn't picked up instead.
+		err := os.Remove(filename)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	dat, err := json.Marshal(savedSession{
+		SessionId:        *id,
+		Sequence:         getSequence(),
+		ResumeGatewayUrl: resumeUrl,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(filename), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, dat, 0600)
+}
+
+// Load and remove the saved session, so it is only tried once. Returns nil if there is none.
+func loadSession(filename string) (*savedSession, error) {
+	dat, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = os.Remove(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var result savedSession
+	err = json.Unmarshal(dat, &result)
+	if err != nil {
+		return nil, err
+	}
+	if result.SessionId == "" {
+		return nil, nil
+	}
+	return &result, nil
+}
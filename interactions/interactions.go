@@ -0,0 +1,257 @@
+// Package interactions receives interactions over HTTP, for use as the application's Interactions Endpoint
+// URL instead of (or alongside) a gateway connection.
+package interactions
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/haplesspanda/haplessbot/commands"
+	"github.com/haplesspanda/haplessbot/rest"
+	"github.com/haplesspanda/haplessbot/types"
+	"github.com/haplesspanda/haplessbot/worker"
+)
+
+// Discord fails the interaction if there is no response within 3 seconds, so defer anything slower than this.
+const responseDeadline = 2500 * time.Millisecond
+
+const maxBodySize = 1 << 20
+
+type Handler struct {
+	publicKey ed25519.PublicKey
+	pool      *worker.Pool
+	client    *rest.Client
+	run       func(context.Context, types.InteractionCreateDetails)
+}
+
+// ParsePublicKey parses the hex encoded public key shown in the developer portal.
+func ParsePublicKey(publicKey string) (ed25519.PublicKey, error) {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key has %d bytes, expected %d", len(key), ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// NewHandler verifies and answers interaction requests, running commands with run on pool. Responses that
+// aren't ready in time (or that upload files) are deferred and then edited in with client.
+func NewHandler(publicKey ed25519.PublicKey, pool *worker.Pool, client *rest.Client, run func(context.Context, types.InteractionCreateDetails)) *Handler {
+	return &Handler{
+		publicKey: publicKey,
+		pool:      pool,
+		client:    client,
+		run:       run,
+	}
+}
+
+// Serve listens on address until ctx is cancelled, then waits up to shutdownTimeout for running commands.
+func Serve(ctx context.Context, address string, handler *Handler, shutdownTimeout time.Duration) error {
+	server := &http.Server{Addr: address, Handler: handler}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Listening for interactions on %s", address)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down, waiting up to %s for in-flight interactions", shutdownTimeout)
+	deadline := time.Now().Add(shutdownTimeout)
+	shutdownCtx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	err := server.Shutdown(shutdownCtx)
+	if err != nil {
+		log.Printf("HTTP shutdown error: %s", err)
+	}
+	if !handler.pool.Shutdown(time.Until(deadline)) {
+		log.Printf("Interactions still running after %s, stopping anyway", shutdownTimeout)
+	}
+	return nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !h.verify(r.Header, body) {
+		log.Printf("Rejecting interaction with invalid signature")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var details types.InteractionCreateDetails
+	err = json.Unmarshal(body, &details)
+	if err != nil {
+		log.Printf("Failed to parse interaction: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if details.Type == 1 { // Ping
+		writeResponse(w, types.InteractionCallbackMessage{Type: 1})
+		return
+	}
+
+	response := newPendingResponse(h.client, details.Token, details.Type)
+	submitted := h.pool.Submit(fmt.Sprintf("interaction %s", details.Id), func(ctx context.Context) {
+		h.run(commands.WithInitialResponder(ctx, response.respond), details)
+	})
+	if !submitted {
+		log.Printf("Interaction queue full (depth %d), dropping interaction %s", h.pool.QueueDepth(), details.Id)
+		writeResponse(w, commands.BusyResponse(details))
+		return
+	}
+
+	timer := time.NewTimer(responseDeadline)
+	defer timer.Stop()
+	select {
+	case message := <-response.ready:
+		writeResponse(w, message)
+	case <-timer.C:
+		writeResponse(w, response.deferNow())
+	case <-r.Context().Done():
+	}
+}
+
+// Check the request was signed by Discord, see https://discord.com/developers/docs/interactions/overview#setting-up-an-endpoint
+func (h *Handler) verify(header http.Header, body []byte) bool {
+	signature, err := hex.DecodeString(header.Get("X-Signature-Ed25519"))
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return false
+	}
+	timestamp := header.Get("X-Signature-Timestamp")
+	if timestamp == "" {
+		return false
+	}
+
+	message := bytes.NewBufferString(timestamp)
+	message.Write(body)
+	return ed25519.Verify(h.publicKey, message.Bytes(), signature)
+}
+
+func writeResponse(w http.ResponseWriter, message types.InteractionCallbackMessage) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(message)
+	if err != nil {
+		log.Printf("Failed to write interaction response: %s", err)
+	}
+}
+
+// Hands the command's initial response over to the HTTP request, or edits it in after the request was
+// answered with a deferred response.
+type pendingResponse struct {
+	lock     sync.Mutex
+	client   *rest.Client
+	token    string
+	ready    chan types.InteractionCallbackMessage
+	answered bool
+	deferred bool
+	// Interaction type, components are deferred without showing a new loading message.
+	interactionType int
+	// The deferred response that was sent, 5 (loading message) or 6 (update the component's message later), and
+	// its flags, which decide whether the edited-in response is ephemeral.
+	deferredType  int
+	deferredFlags int
+}
+
+func newPendingResponse(client *rest.Client, token string, interactionType int) *pendingResponse {
+	return &pendingResponse{
+		client:          client,
+		token:           token,
+		interactionType: interactionType,
+		ready:           make(chan types.InteractionCallbackMessage, 1),
+	}
+}
+
+func (p *pendingResponse) respond(response types.InteractionCallbackMessage, files []rest.BinaryAttachment) error {
+	p.lock.Lock()
+	if p.answered {
+		p.lock.Unlock()
+		return errors.New("interaction already responded to")
+	}
+	if !p.deferred && len(files) == 0 {
+		p.answered = true
+		p.ready <- response
+		p.lock.Unlock()
+		return nil
+	}
+	if !p.deferred {
+		// Files need a multipart request, so defer and upload them with an edit instead.
+		p.deferred = true
+		p.deferredType = 5
+		if response.Type == 7 {
+			p.deferredType = 6
+		}
+		p.deferredFlags = response.Data.Flags & types.MessageFlagEphemeral
+		p.ready <- types.InteractionCallbackMessage{Type: p.deferredType, Data: types.InteractionCallbackData{Flags: p.deferredFlags}}
+	}
+	ephemeral := response.Data.Flags&types.MessageFlagEphemeral != 0
+	deferredType := p.deferredType
+	deferredEphemeral := p.deferredFlags&types.MessageFlagEphemeral != 0
+	p.answered = true
+	p.lock.Unlock()
+
+	if response.Type == 5 || response.Type == 6 {
+		// Already deferred.
+		return nil
+	}
+	if deferredType == 6 && response.Type != 7 {
+		// Nothing to fill in, the response is a new message.
+		_, err := p.client.CreateFollowup(context.Background(), p.token, response.Data, files...)
+		return err
+	}
+	if deferredType == 5 && ephemeral && !deferredEphemeral {
+		// Whether a response is ephemeral is fixed by the deferral, so swap the loading message for a followup.
+		err := p.client.DeleteOriginalResponse(context.Background(), p.token)
+		if err != nil {
+			return err
+		}
+		_, err = p.client.CreateFollowup(context.Background(), p.token, response.Data, files...)
+		return err
+	}
+	response.Data.Flags &^= types.MessageFlagEphemeral
+	_, err := p.client.EditOriginalResponse(context.Background(), p.token, response.Data, files...)
+	return err
+}
+
+// Answer the request with a deferred response, unless the command's response arrived in the meantime.
+func (p *pendingResponse) deferNow() types.InteractionCallbackMessage {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	select {
+	case message := <-p.ready:
+		return message
+	default:
+	}
+	p.deferred = true
+	p.deferredType = 5
+	if p.interactionType == 3 { // Message component
+		p.deferredType = 6
+	}
+	return types.InteractionCallbackMessage{Type: p.deferredType}
+}
@@ -1,15 +1,25 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/haplesspanda/haplessbot/commands"
+	"github.com/haplesspanda/haplessbot/config"
+	"github.com/haplesspanda/haplessbot/constants"
 	"github.com/haplesspanda/haplessbot/gateway"
+	"github.com/haplesspanda/haplessbot/interactions"
+	"github.com/haplesspanda/haplessbot/rest"
+	"github.com/haplesspanda/haplessbot/types"
+	"github.com/haplesspanda/haplessbot/usercache"
+	"github.com/haplesspanda/haplessbot/worker"
 )
 
 var logfile *os.File
@@ -29,25 +39,145 @@ func init() {
 
 func main() {
 	fmt.Println("Starting up bot operations...")
+	constants.Load()
 
-	defineCommands := flag.String("define_commands", "", "Comma-separated list of commands to push, if any")
+	syncCommands := flag.Bool("sync_commands", false, "Update the commands registered with Discord to match the bot's, then start")
+	dryRun := flag.Bool("dry_run", false, "Only print what --sync_commands would change, then exit")
+	syncGuild := flag.String("guild", "", "Sync every command to this guild only, where changes show up instantly")
+	configFile := flag.String("config", "config.json", "Path to the bot config file")
+	intentsOverride := flag.String("intents", "", "Comma-separated list of gateway intents, overrides the config file")
+	httpInteractions := flag.String("http_interactions", "", "Address to receive interactions over HTTP on (e.g. :8080) instead of connecting to the gateway")
 	flag.Parse()
 
-	parsedCommands := strings.Split(*defineCommands, ",")
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		panic(err)
+	}
+	if *intentsOverride != "" {
+		cfg.Intents = strings.Split(*intentsOverride, ",")
+	}
+	if *httpInteractions != "" {
+		cfg.InteractionsAddress = *httpInteractions
+	}
+	intents, err := gateway.ParseIntents(cfg.Intents)
+	if err != nil {
+		panic(err)
+	}
 
-	if len(parsedCommands) != 0 && !(len(parsedCommands) == 1 && parsedCommands[0] == "") {
-		commands.DefineCommands(parsedCommands)
+	client := rest.NewClient(constants.ApplicationId)
+	client.BaseUrl = cfg.ApiBaseUrl
+	if cfg.UserCacheTtlSeconds > 0 {
+		client.Users = usercache.New(time.Duration(cfg.UserCacheTtlSeconds) * time.Second)
+	}
+	commands.SetClient(client)
+
+	// Stop on Ctrl+C as well as SIGTERM from systemd or Docker.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	commands.SetGuildCommands(cfg.GuildCommands)
+	if *syncCommands || *dryRun {
+		err = commands.SyncCommands(ctx, *syncGuild, *dryRun)
+		if err != nil {
+			panic(err)
+		}
+		if *dryRun {
+			closeLogfile()
+			return
+		}
 	} else {
-		log.Println("No commands to push, skipping")
+		log.Println("Not syncing commands, skipping")
 	}
 
-	gateway.StartConnection()
+	commands.SetOwners(cfg.Owners)
+	commands.SetCommandRoles(cfg.CommandRoles)
+	cooldowns := make(map[string]commands.Cooldown)
+	for name, cooldown := range cfg.Cooldowns {
+		per := cooldown.Per
+		if per == "" {
+			per = "user"
+		}
+		cooldowns[name] = commands.Cooldown{Duration: time.Duration(cooldown.Seconds) * time.Second, Per: per}
+	}
+	err = commands.SetCooldowns(cooldowns)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid cooldowns in config: %s", err))
+	}
+	if cfg.CooldownFile != "" {
+		err = commands.LoadCooldowns(cfg.CooldownFile)
+		if err != nil {
+			log.Printf("Failed to load saved cooldowns: %s", err)
+		}
+	}
 
-	// Cleanup logic below.
-	err := logfile.Close()
+	if cfg.InteractionsAddress != "" {
+		publicKey, err := interactions.ParsePublicKey(cfg.PublicKey)
+		if err != nil {
+			panic(fmt.Sprintf("Invalid public_key in config: %s", err))
+		}
+		pool := worker.NewPool(cfg.Workers, cfg.WorkerQueueDepth, time.Duration(cfg.InteractionTimeoutSeconds)*time.Second)
+		handler := interactions.NewHandler(publicKey, pool, client, commands.RunInteractionCallback)
+		err = interactions.Serve(ctx, cfg.InteractionsAddress, handler, time.Duration(cfg.ShutdownTimeoutSeconds)*time.Second)
+		if err != nil {
+			log.Printf("Interactions server failed: %s", err)
+		}
+	} else {
+		gateway.OnInteractionCreate(commands.RunInteractionCallback)
+		if client.Users != nil {
+			invalidateGatewayUsers(client.Users)
+		}
+		gateway.StartConnection(ctx, gateway.Options{
+			Client:     client,
+			GatewayUrl: cfg.GatewayUrl,
+			Intents:    intents,
+			Presence: gateway.PresenceOptions{
+				Status:         cfg.Presence.Status,
+				Activities:     cfg.Presence.Activities,
+				RotateInterval: time.Duration(cfg.Presence.RotateIntervalSeconds) * time.Second,
+			},
+			SessionFile:        cfg.SessionFile,
+			ShutdownTimeout:    time.Duration(cfg.ShutdownTimeoutSeconds) * time.Second,
+			Workers:            cfg.Workers,
+			WorkerQueueDepth:   cfg.WorkerQueueDepth,
+			InteractionTimeout: time.Duration(cfg.InteractionTimeoutSeconds) * time.Second,
+			BusyResponse:       commands.BusyResponse,
+		})
+		stats := gateway.GetSendStats()
+		log.Printf("Gateway sends: %d sent, %d throttled by the send rate limit", stats.Sent, stats.Throttled)
+	}
+
+	if cfg.CooldownFile != "" {
+		err = commands.SaveCooldowns(cfg.CooldownFile)
+		if err != nil {
+			log.Printf("Failed to save cooldowns: %s", err)
+		}
+	}
+
+	if client.Users != nil {
+		stats := client.Users.Stats()
+		log.Printf("User cache: %d hits, %d misses, %d invalidations, %d entries", stats.Hits, stats.Misses, stats.Invalidations, stats.Entries)
+	}
+
+	// Cleanup once everything that might still log is done.
+	closeLogfile()
+
+	fmt.Println("Done")
+}
+
+func closeLogfile() {
+	err := logfile.Sync()
 	if err != nil {
 		panic(err)
 	}
+	err = logfile.Close()
+	if err != nil {
+		panic(err)
+	}
+}
 
-	fmt.Println("Done")
+// Drop cached users when member events say they changed, which only arrive with the guild_members intent.
+func invalidateGatewayUsers(users *usercache.Cache) {
+	gateway.OnGuildMemberUpdate(func(event types.GuildMemberUpdateEvent) {
+		users.Invalidate(event.User.Id)
+	})
 }
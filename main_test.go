@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haplesspanda/haplessbot/commands"
+	"github.com/haplesspanda/haplessbot/fakediscord"
+	"github.com/haplesspanda/haplessbot/gateway"
+	"github.com/haplesspanda/haplessbot/interactions"
+	"github.com/haplesspanda/haplessbot/rest"
+	"github.com/haplesspanda/haplessbot/types"
+	"github.com/haplesspanda/haplessbot/worker"
+)
+
+const responseTimeout = 5 * time.Second
+
+// The fake Discord the bot is connected to, shared by all tests since gateway handlers are registered per process.
+var fake *fakediscord.Server
+
+func TestMain(m *testing.M) {
+	fake = fakediscord.New()
+	client := rest.NewClient(42)
+	client.BaseUrl = fake.ApiUrl()
+	commands.SetClient(client)
+	gateway.OnInteractionCreate(commands.RunInteractionCallback)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		gateway.StartConnection(ctx, gateway.Options{
+			Client:             client,
+			GatewayUrl:         fake.GatewayUrl(),
+			ShutdownTimeout:    responseTimeout,
+			Workers:            4,
+			WorkerQueueDepth:   16,
+			InteractionTimeout: responseTimeout,
+			BusyResponse:       commands.BusyResponse,
+		})
+		close(stopped)
+	}()
+	err := fake.WaitForReady(responseTimeout)
+	if err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+
+	cancel()
+	<-stopped
+	fake.Close()
+	os.Exit(code)
+}
+
+// Send a slash command over the gateway and return the bot's response to it.
+func runCommand(t *testing.T, token string, data types.InteractionData) fakediscord.Request {
+	t.Helper()
+	err := fake.SendInteraction(types.InteractionCreateDetails{
+		Id:        token,
+		Token:     token,
+		GuildId:   "1000",
+		ChannelId: "2000",
+		Member:    types.GuildMemberData{User: types.UserData{Id: "3000", Username: "eirika", Discriminator: "0"}},
+		Data:      data,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := fake.WaitForResponse(token, responseTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func parseResponse(t *testing.T, request fakediscord.Request) types.InteractionCallbackMessage {
+	t.Helper()
+	var message types.InteractionCallbackMessage
+	err := json.Unmarshal(request.Payload, &message)
+	if err != nil {
+		t.Fatalf("invalid response %s: %s", request.Payload, err)
+	}
+	return message
+}
+
+func TestPing(t *testing.T) {
+	response := runCommand(t, "ping-token", types.InteractionData{Type: 1, Name: "ping"})
+	message := parseResponse(t, response)
+	if message.Type != 4 || message.Data.Content != "Pong" {
+		t.Errorf("got %s, want a Pong message", response.Payload)
+	}
+	if len(response.Files) != 0 {
+		t.Errorf("got %d files, want none", len(response.Files))
+	}
+}
+
+func TestAvatar(t *testing.T) {
+	hash := "a_1234"
+	user := types.UserData{Id: "4000", Username: "ephraim", Discriminator: "0", Avatar: &hash}
+	response := runCommand(t, "avatar-token", types.InteractionData{
+		Type:     1,
+		Name:     "avatar",
+		Options:  []types.Option{{Name: "user", Type: 6, Value: user.Id}},
+		Resolved: types.ResolvedEntities{Users: map[string]types.UserData{user.Id: user}},
+	})
+	message := parseResponse(t, response)
+
+	if message.Type != 4 || len(message.Data.Embeds) != 1 {
+		t.Fatalf("got %s, want one embed", response.Payload)
+	}
+	embed := message.Data.Embeds[0]
+	if embed.Title != "Avatar for ephraim#0" {
+		t.Errorf("got title %q", embed.Title)
+	}
+	// Animated avatars default to GIF.
+	if want := "https://cdn.discordapp.com/avatars/4000/a_1234.gif?size=4096"; embed.Image.Url != want {
+		t.Errorf("got image %q, want %q", embed.Image.Url, want)
+	}
+	if len(response.Files) != 0 {
+		t.Errorf("got %d files, want none", len(response.Files))
+	}
+}
+
+func TestFe8CharacterInfo(t *testing.T) {
+	response := runCommand(t, "fe8-token", types.InteractionData{Type: 1, Name: "fe8", Options: []types.Option{{
+		Name: "character", Type: 2, Options: []types.Option{{
+			Name: "info", Type: 1, Options: []types.Option{{Name: "character", Type: 3, Value: "Eirika"}},
+		}},
+	}}})
+	message := parseResponse(t, response)
+
+	if message.Type != 4 || len(message.Data.Embeds) != 1 || message.Data.Embeds[0].Title != "Eirika" {
+		t.Fatalf("got %s, want an embed for Eirika", response.Payload)
+	}
+	if len(message.Data.Components) == 0 {
+		t.Errorf("got no buttons")
+	}
+	if len(response.Files) != 1 {
+		t.Errorf("got %d files, want the portrait", len(response.Files))
+	}
+}
+
+func TestResume(t *testing.T) {
+	identifies, resumes := fake.SessionStarts()
+	err := fake.RequestReconnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(responseTimeout)
+	for {
+		newIdentifies, newResumes := fake.SessionStarts()
+		if newResumes == resumes+1 {
+			if newIdentifies != identifies {
+				t.Errorf("identified again after reconnect")
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no resume after %s", responseTimeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Interactions still arrive on the resumed session.
+	runCommand(t, "resumed-token", types.InteractionData{Type: 1, Name: "ping"})
+}
+
+func TestHttpInteractionSignature(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := worker.NewPool(1, 1, responseTimeout)
+	defer pool.Shutdown(responseTimeout)
+	handler := interactions.NewHandler(publicKey, pool, nil, commands.RunInteractionCallback)
+
+	post := func(signature []byte) *httptest.ResponseRecorder {
+		body := []byte(`{"type":1,"id":"5000","token":"http-token"}`)
+		timestamp := "1700000000"
+		request := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+		request.Header.Set("X-Signature-Timestamp", timestamp)
+		if signature == nil {
+			signature = ed25519.Sign(privateKey, append([]byte(timestamp), body...))
+		}
+		request.Header.Set("X-Signature-Ed25519", hex.EncodeToString(signature))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	invalid := post(make([]byte, ed25519.SignatureSize))
+	if invalid.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d for an invalid signature, want 401", invalid.Code)
+	}
+
+	valid := post(nil)
+	if valid.Code != http.StatusOK {
+		t.Fatalf("got status %d for a valid signature, want 200", valid.Code)
+	}
+	var message types.InteractionCallbackMessage
+	err = json.Unmarshal(valid.Body.Bytes(), &message)
+	if err != nil || message.Type != 1 {
+		t.Errorf("got %s, want a pong", valid.Body.Bytes())
+	}
+}
+
+func TestComponentSkipsCooldown(t *testing.T) {
+	err := commands.SetCooldowns(map[string]commands.Cooldown{"fe8": {Duration: time.Minute, Per: "user"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer commands.SetCooldowns(map[string]commands.Cooldown{})
+
+	// The command starts the cooldown, clicking its buttons right after shouldn't trip it.
+	user := types.UserData{Id: "6000", Username: "seth", Discriminator: "0"}
+	err = fake.SendInteraction(types.InteractionCreateDetails{
+		Id:      "cooldown-token",
+		Token:   "cooldown-token",
+		GuildId: "1000",
+		Member:  types.GuildMemberData{User: user},
+		Data: types.InteractionData{Type: 1, Name: "fe8", Options: []types.Option{{
+			Name: "character", Type: 2, Options: []types.Option{{
+				Name: "info", Type: 1, Options: []types.Option{{Name: "character", Type: 3, Value: "Eirika"}},
+			}},
+		}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fake.WaitForResponse("cooldown-token", responseTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = fake.SendInteraction(types.InteractionCreateDetails{
+		Type:    3,
+		Id:      "click-token",
+		Token:   "click-token",
+		GuildId: "1000",
+		Member:  types.GuildMemberData{User: user},
+		Message: &types.Message{Interaction: &types.MessageInteraction{Name: "fe8 character info", User: user}},
+		Data:    types.InteractionData{CustomId: "fe8:Eirika:average:10", ComponentType: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := fake.WaitForResponse("click-token", responseTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message := parseResponse(t, response); message.Type != 7 {
+		t.Errorf("got %s, want the message updated", response.Payload)
+	}
+}
+
+func TestBannerLookupFailure(t *testing.T) {
+	// The fake doesn't know the user, so the lookup fails with a 404.
+	response := runCommand(t, "banner-token", types.InteractionData{
+		Type:    1,
+		Name:    "banner",
+		Options: []types.Option{{Name: "user", Type: 6, Value: "7000"}},
+	})
+	message := parseResponse(t, response)
+	if message.Data.Flags&types.MessageFlagEphemeral == 0 {
+		t.Errorf("got %s, want an ephemeral reply", response.Payload)
+	}
+	if !strings.HasPrefix(message.Data.Content, "Couldn't look up that user, try again later (error ID `") {
+		t.Errorf("got %q, want the lookup error with an error ID", message.Data.Content)
+	}
+}
@@ -0,0 +1,205 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/haplesspanda/haplessbot/types"
+	"github.com/haplesspanda/haplessbot/usercache"
+)
+
+const DefaultBaseUrl = "https://discord.com/api/v10"
+
+// Client for the Discord REST endpoints the bot uses.
+type Client struct {
+	BaseUrl       string
+	ApplicationId int
+	// Where GetUser looks users up first, if set.
+	Users *usercache.Cache
+}
+
+func NewClient(applicationId int) *Client {
+	return &Client{
+		BaseUrl:       DefaultBaseUrl,
+		ApplicationId: applicationId,
+	}
+}
+
+// Error response from Discord, see https://discord.com/developers/docs/reference#error-messages
+type APIError struct {
+	StatusCode int
+	Code       int             `json:"code"`
+	Message    string          `json:"message"`
+	Errors     json.RawMessage `json:"errors"`
+}
+
+func (e *APIError) Error() string {
+	if len(e.Errors) > 0 {
+		return fmt.Sprintf("discord API error %d (HTTP %d): %s %s", e.Code, e.StatusCode, e.Message, e.Errors)
+	}
+	return fmt.Sprintf("discord API error %d (HTTP %d): %s", e.Code, e.StatusCode, e.Message)
+}
+
+// GetGateway returns the URL to open gateway connections to.
+func (c *Client) GetGateway(ctx context.Context) (string, error) {
+	var result struct {
+		Url string `json:"url"`
+	}
+	err := c.do(ctx, "GET", "/gateway", nil, nil, &result)
+	if err != nil {
+		return "", err
+	}
+	return result.Url, nil
+}
+
+func (c *Client) CreateInteractionResponse(ctx context.Context, interactionId string, token string, response types.InteractionCallbackMessage, files ...BinaryAttachment) error {
+	response.Data = withAttachments(response.Data, files)
+	return c.do(ctx, "POST", fmt.Sprintf("/interactions/%s/%s/callback", interactionId, token), response, files, nil)
+}
+
+func (c *Client) EditOriginalResponse(ctx context.Context, token string, data types.InteractionCallbackData, files ...BinaryAttachment) (*types.Message, error) {
+	var result types.Message
+	err := c.do(ctx, "PATCH", fmt.Sprintf("/webhooks/%d/%s/messages/@original", c.ApplicationId, token), withAttachments(data, files), files, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteOriginalResponse removes the initial response, e.g. a "thinking..." message that won't be filled in.
+func (c *Client) DeleteOriginalResponse(ctx context.Context, token string) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/webhooks/%d/%s/messages/@original", c.ApplicationId, token), nil, nil, nil)
+}
+
+func (c *Client) CreateFollowup(ctx context.Context, token string, data types.InteractionCallbackData, files ...BinaryAttachment) (*types.Message, error) {
+	var result types.Message
+	err := c.do(ctx, "POST", fmt.Sprintf("/webhooks/%d/%s", c.ApplicationId, token), withAttachments(data, files), files, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetUser looks up a user's full profile, from Users if it was looked up recently.
+func (c *Client) GetUser(ctx context.Context, userId string) (*types.UserData, error) {
+	if c.Users != nil {
+		if cached, ok := c.Users.Get(userId); ok {
+			return &cached, nil
+		}
+	}
+
+	var result types.UserData
+	err := c.do(ctx, "GET", fmt.Sprintf("/users/%s", userId), nil, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	if c.Users != nil {
+		c.Users.Put(result)
+	}
+	return &result, nil
+}
+
+// GetCommands lists the registered global commands.
+func (c *Client) GetCommands(ctx context.Context) ([]types.ApplicationCommand, error) {
+	return c.GetGuildCommands(ctx, "")
+}
+
+// GetGuildCommands lists the commands registered only in the given guild, or the global ones if guildId is empty.
+func (c *Client) GetGuildCommands(ctx context.Context, guildId string) ([]types.ApplicationCommand, error) {
+	var result []types.ApplicationCommand
+	// Without this, Discord leaves out the translations and only sends the ones for the bot's own locale.
+	err := c.do(ctx, "GET", c.commandsPath(guildId)+"?with_localizations=true", nil, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Client) CreateCommand(ctx context.Context, command types.ApplicationCommand) (*types.ApplicationCommand, error) {
+	var result types.ApplicationCommand
+	err := c.do(ctx, "POST", c.commandsPath(""), command, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// BulkOverwriteCommands replaces all global commands with the given ones.
+func (c *Client) BulkOverwriteCommands(ctx context.Context, commands []types.ApplicationCommand) ([]types.ApplicationCommand, error) {
+	return c.BulkOverwriteGuildCommands(ctx, "", commands)
+}
+
+// BulkOverwriteGuildCommands replaces all of a guild's commands with the given ones, or the global ones if guildId
+// is empty. Guild commands update instantly, unlike global ones.
+func (c *Client) BulkOverwriteGuildCommands(ctx context.Context, guildId string, commands []types.ApplicationCommand) ([]types.ApplicationCommand, error) {
+	var result []types.ApplicationCommand
+	err := c.do(ctx, "PUT", c.commandsPath(guildId), commands, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Client) commandsPath(guildId string) string {
+	if guildId == "" {
+		return fmt.Sprintf("/applications/%d/commands", c.ApplicationId)
+	}
+	return fmt.Sprintf("/applications/%d/guilds/%s/commands", c.ApplicationId, guildId)
+}
+
+// Declare uploaded files in the message's attachments, unless the caller already did.
+func withAttachments(data types.InteractionCallbackData, files []BinaryAttachment) types.InteractionCallbackData {
+	if len(files) > 0 && data.Attachments == nil {
+		data.Attachments = AttachmentMetadata(files)
+	}
+	return data
+}
+
+// Send payload as JSON (or a multipart form, with files) and decode the response into result if not nil.
+func (c *Client) do(ctx context.Context, method string, path string, payload any, files []BinaryAttachment, result any) error {
+	var body io.Reader
+	contentType := "application/json"
+	if payload != nil {
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			body = bytes.NewBuffer(payloadBytes)
+		} else {
+			form, boundary, err := MultiPartForm(payloadBytes, files)
+			if err != nil {
+				return err
+			}
+			body = form
+			contentType = fmt.Sprintf("multipart/form-data; boundary=%s", boundary)
+		}
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, c.BaseUrl+path, body)
+	if err != nil {
+		return err
+	}
+
+	statusCode, responseBody, err := doRequest(request, contentType)
+	if err != nil {
+		return err
+	}
+	if statusCode < 200 || statusCode >= 300 {
+		apiError := APIError{StatusCode: statusCode}
+		err = json.Unmarshal(responseBody, &apiError)
+		if err != nil {
+			apiError.Message = string(responseBody)
+		}
+		return &apiError
+	}
+
+	if result == nil || len(responseBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(responseBody, result)
+}
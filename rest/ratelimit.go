@@ -0,0 +1,275 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haplesspanda/haplessbot/sweep"
+)
+
+// Rate limit state for one bucket, see https://discord.com/developers/docs/topics/rate-limits
+//
+// The lock only guards the counts, requests are sent without holding it so a bucket with requests left doesn't send
+// them one at a time.
+type bucket struct {
+	lock sync.Mutex
+	// Requests left until reset, not counting the ones in flight.
+	remaining int
+	// Requests allowed per window, 0 until Discord reports it. Buckets with unknown limits don't hold requests back.
+	limit int
+	reset time.Time
+	// How long a window lasts, the longest X-RateLimit-Reset-After seen.
+	window time.Duration
+	// Requests sent that haven't had a response yet.
+	inFlight int
+}
+
+type rateLimiter struct {
+	lock sync.Mutex
+	// Route template (e.g. "POST /channels/{channel_id}/messages") to the bucket hash Discord reported for it.
+	routeBuckets map[string]string
+	// Bucket hash plus major parameters to bucket state.
+	buckets     map[string]*bucket
+	globalReset time.Time
+	sweeps      sweep.Schedule
+}
+
+var limiter = newRateLimiter()
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		routeBuckets: make(map[string]string),
+		buckets:      make(map[string]*bucket),
+	}
+}
+
+// Segments whose following ID gets its own rate limit. Webhook tokens are major parameters too.
+var majorParameters = map[string]string{
+	"channels": "{channel_id}",
+	"guilds":   "{guild_id}",
+	"webhooks": "{webhook_id}",
+}
+
+// Split a request into its route template and the values of its major parameters.
+func routeFor(method string, requestUrl *url.URL) (string, string) {
+	segments := strings.Split(strings.Trim(requestUrl.Path, "/"), "/")
+	route := make([]string, 0, len(segments))
+	majors := make([]string, 0)
+	for i, segment := range segments {
+		if i == 0 && segment == "api" {
+			continue
+		}
+		if i > 0 {
+			if placeholder, ok := majorParameters[segments[i-1]]; ok {
+				route = append(route, placeholder)
+				majors = append(majors, segment)
+				continue
+			}
+		}
+		if i > 1 && segments[i-2] == "webhooks" {
+			route = append(route, "{token}")
+			majors = append(majors, segment)
+			continue
+		}
+		if i > 1 && segments[i-2] == "interactions" {
+			route = append(route, "{token}")
+			continue
+		}
+		if isSnowflake(segment) {
+			route = append(route, "{id}")
+			continue
+		}
+		route = append(route, segment)
+	}
+	return method + " /" + strings.Join(route, "/"), strings.Join(majors, "/")
+}
+
+func isSnowflake(segment string) bool {
+	if segment == "" {
+		return false
+	}
+	for _, c := range segment {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Interaction callbacks aren't bound to the global rate limit, and every interaction only gets one, so they're sent
+// right away.
+func exempt(route string) bool {
+	return strings.HasSuffix(route, "/interactions/{id}/{token}/callback")
+}
+
+func (l *rateLimiter) bucketFor(route string, majors string) *bucket {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	key := route
+	if hash, ok := l.routeBuckets[route]; ok {
+		key = hash
+	}
+	key = key + ":" + majors
+
+	result, ok := l.buckets[key]
+	if !ok {
+		result = &bucket{}
+		l.buckets[key] = result
+		sweep.Expired(&l.sweeps, l.buckets, bucketReset(time.Now()))
+	}
+	return result
+}
+
+// Remember which bucket a route belongs to, moving over any state tracked under the route so far.
+func (l *rateLimiter) setRouteBucket(route string, majors string, hash string, current *bucket) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.routeBuckets[route] == hash {
+		return
+	}
+	l.routeBuckets[route] = hash
+	key := hash + ":" + majors
+	if _, ok := l.buckets[key]; !ok {
+		l.buckets[key] = current
+		sweep.Expired(&l.sweeps, l.buckets, bucketReset(time.Now()))
+	}
+}
+
+// Whether a bucket has reset and can be forgotten, since a new bucket starts out the same. Buckets with requests in
+// flight are kept, their responses bring new limits.
+func bucketReset(now time.Time) func(*bucket) bool {
+	return func(b *bucket) bool {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		return b.inFlight == 0 && !now.Before(b.reset)
+	}
+}
+
+func (l *rateLimiter) setGlobalReset(reset time.Time) {
+	l.lock.Lock()
+	if reset.After(l.globalReset) {
+		l.globalReset = reset
+	}
+	l.lock.Unlock()
+}
+
+func (l *rateLimiter) waitGlobal(ctx context.Context) error {
+	l.lock.Lock()
+	wait := time.Until(l.globalReset)
+	l.lock.Unlock()
+	return sleep(ctx, wait)
+}
+
+// Wait for the bucket to have a request left and take it. Every take needs a done or cancel.
+func (b *bucket) take(ctx context.Context) error {
+	for {
+		b.lock.Lock()
+		now := time.Now()
+		if b.remaining <= 0 && b.limit > 0 && !now.Before(b.reset) {
+			// A new window, assume it's full until a response says otherwise.
+			b.remaining = b.limit
+			b.reset = now.Add(b.window)
+		}
+		if b.remaining > 0 || b.limit == 0 {
+			b.remaining--
+			b.inFlight++
+			b.lock.Unlock()
+			return nil
+		}
+		wait := b.reset.Sub(now)
+		b.lock.Unlock()
+
+		err := sleep(ctx, wait)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Give back a request that was taken but got no response.
+func (b *bucket) cancel() {
+	b.lock.Lock()
+	b.remaining++
+	b.inFlight--
+	b.lock.Unlock()
+}
+
+// Update bucket state from a response's X-RateLimit-* headers, returning the bucket hash if present.
+func (b *bucket) done(header http.Header) string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.inFlight--
+
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err == nil {
+		b.limit = limit
+	}
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err == nil {
+		// Discord hasn't counted the requests still in flight yet.
+		b.remaining = remaining - b.inFlight
+	}
+	resetAfter, err := strconv.ParseFloat(header.Get("X-RateLimit-Reset-After"), 64)
+	if err == nil {
+		duration := secondsToDuration(resetAfter)
+		b.reset = time.Now().Add(duration)
+		if duration > b.window {
+			b.window = duration
+		}
+	}
+	return header.Get("X-RateLimit-Bucket")
+}
+
+// Hold requests back until retryAfter has passed, after a 429.
+func (b *bucket) limited(retryAfter time.Duration) {
+	b.lock.Lock()
+	b.remaining = 0
+	b.reset = time.Now().Add(retryAfter)
+	if b.limit == 0 {
+		// Hold requests back even though the limit is unknown.
+		b.limit = 1
+	}
+	b.lock.Unlock()
+}
+
+// How long a 429 response asks us to wait, and whether it applies to all requests.
+func parseRateLimited(header http.Header, body []byte) (time.Duration, bool) {
+	var rateLimitJson struct {
+		RetryAfter float64 `json:"retry_after"`
+		Global     bool    `json:"global"`
+	}
+	json.Unmarshal(body, &rateLimitJson)
+
+	retryAfter := rateLimitJson.RetryAfter
+	if headerRetryAfter, err := strconv.ParseFloat(header.Get("Retry-After"), 64); err == nil && headerRetryAfter > retryAfter {
+		retryAfter = headerRetryAfter
+	}
+	global := rateLimitJson.Global || header.Get("X-RateLimit-Global") == "true"
+	return secondsToDuration(retryAfter), global
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func sleep(ctx context.Context, duration time.Duration) error {
+	if duration <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
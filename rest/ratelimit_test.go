@@ -0,0 +1,218 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+func TestRouteFor(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		route  string
+		majors string
+	}{
+		{"POST", "/api/v10/channels/111/messages", "POST /v10/channels/{channel_id}/messages", "111"},
+		{"DELETE", "/api/v10/channels/111/messages/222", "DELETE /v10/channels/{channel_id}/messages/{id}", "111"},
+		{"GET", "/api/v10/guilds/333/members/444", "GET /v10/guilds/{guild_id}/members/{id}", "333"},
+		{"GET", "/api/v10/users/444", "GET /v10/users/{id}", ""},
+		{"PUT", "/api/v10/applications/42/guilds/333/commands", "PUT /v10/applications/{id}/guilds/{guild_id}/commands", "333"},
+		{"POST", "/api/v10/interactions/555/abc.def/callback", "POST /v10/interactions/{id}/{token}/callback", ""},
+		{"PATCH", "/api/v10/webhooks/42/abc.def/messages/@original", "PATCH /v10/webhooks/{webhook_id}/{token}/messages/@original", "42/abc.def"},
+		{"POST", "/api/v10/webhooks/42/abc.def", "POST /v10/webhooks/{webhook_id}/{token}", "42/abc.def"},
+	}
+	for _, test := range tests {
+		requestUrl, err := url.Parse("https://discord.com" + test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		route, majors := routeFor(test.method, requestUrl)
+		if route != test.route || majors != test.majors {
+			t.Errorf("routeFor(%s %s) = %q, %q, want %q, %q", test.method, test.path, route, majors, test.route, test.majors)
+		}
+	}
+}
+
+func TestExempt(t *testing.T) {
+	if !exempt("POST /v10/interactions/{id}/{token}/callback") {
+		t.Errorf("interaction callbacks should be exempt")
+	}
+	if exempt("POST /v10/webhooks/{webhook_id}/{token}") {
+		t.Errorf("followups shouldn't be exempt")
+	}
+}
+
+func TestParseRateLimited(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     map[string]string
+		body       string
+		retryAfter time.Duration
+		global     bool
+	}{
+		{"body only", nil, `{"retry_after": 1.5, "global": false}`, 1500 * time.Millisecond, false},
+		{"global in body", nil, `{"retry_after": 0.25, "global": true}`, 250 * time.Millisecond, true},
+		{"global in header", map[string]string{"X-RateLimit-Global": "true", "Retry-After": "2"}, `{}`, 2 * time.Second, true},
+		{"longer header wins", map[string]string{"Retry-After": "3"}, `{"retry_after": 1}`, 3 * time.Second, false},
+		{"unparseable body", map[string]string{"Retry-After": "1"}, `<html>`, time.Second, false},
+	}
+	for _, test := range tests {
+		header := http.Header{}
+		for key, value := range test.header {
+			header.Set(key, value)
+		}
+		retryAfter, global := parseRateLimited(header, []byte(test.body))
+		if retryAfter != test.retryAfter || global != test.global {
+			t.Errorf("%s: got %s, %t, want %s, %t", test.name, retryAfter, global, test.retryAfter, test.global)
+		}
+	}
+}
+
+func TestBucketHashing(t *testing.T) {
+	l := newRateLimiter()
+	messages := l.bucketFor("POST /v10/channels/{channel_id}/messages", "111")
+	if l.bucketFor("POST /v10/channels/{channel_id}/messages", "111") != messages {
+		t.Errorf("same route and majors should share a bucket")
+	}
+	if l.bucketFor("POST /v10/channels/{channel_id}/messages", "222") == messages {
+		t.Errorf("different majors should get their own bucket")
+	}
+
+	// Once Discord says two routes share a bucket, they share its state.
+	l.setRouteBucket("POST /v10/channels/{channel_id}/messages", "111", "abcd", messages)
+	edits := l.bucketFor("PATCH /v10/channels/{channel_id}/messages/{id}", "111")
+	l.setRouteBucket("PATCH /v10/channels/{channel_id}/messages/{id}", "111", "abcd", edits)
+	if l.bucketFor("PATCH /v10/channels/{channel_id}/messages/{id}", "111") != messages {
+		t.Errorf("routes with the same bucket hash should share a bucket")
+	}
+	if l.bucketFor("POST /v10/channels/{channel_id}/messages", "222") == messages {
+		t.Errorf("the same bucket hash with different majors should get its own bucket")
+	}
+}
+
+func TestBucketDone(t *testing.T) {
+	b := &bucket{}
+	for i := 0; i < 3; i++ {
+		err := b.take(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	header := http.Header{}
+	header.Set("X-RateLimit-Limit", "5")
+	header.Set("X-RateLimit-Remaining", "4")
+	header.Set("X-RateLimit-Reset-After", "1.5")
+	header.Set("X-RateLimit-Bucket", "abcd")
+	if hash := b.done(header); hash != "abcd" {
+		t.Errorf("got hash %q, want abcd", hash)
+	}
+	// Two requests are still in flight that Discord hasn't counted yet.
+	if b.remaining != 2 || b.limit != 5 || b.window != 1500*time.Millisecond {
+		t.Errorf("got remaining %d, limit %d, window %s, want 2, 5, 1.5s", b.remaining, b.limit, b.window)
+	}
+}
+
+func TestTakeWaitsForReset(t *testing.T) {
+	b := &bucket{limit: 1, reset: time.Now().Add(100 * time.Millisecond), window: time.Second}
+	start := time.Now()
+	err := b.take(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("took %s, want to wait for the reset", elapsed)
+	}
+}
+
+func TestTakeStopsWithContext(t *testing.T) {
+	b := &bucket{limit: 1, reset: time.Now().Add(time.Hour)}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := b.take(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("got %v, want the context's error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %s, want to stop when the context does", elapsed)
+	}
+}
+
+// Start a server that answers every request after latency, with rate limit headers allowing limit requests.
+func slowServer(t *testing.T, latency time.Duration, limit int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(latency)
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(limit-1))
+		w.Header().Set("X-RateLimit-Reset-After", "1")
+		w.Header().Set("X-RateLimit-Bucket", "abcd")
+		if strings.HasSuffix(r.URL.Path, "/callback") {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// Run concurrently requests at once and return how long they took together.
+func timeConcurrent(t *testing.T, concurrently int, request func(i int) error) time.Duration {
+	start := time.Now()
+	var wait sync.WaitGroup
+	for i := 0; i < concurrently; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			err := request(i)
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wait.Wait()
+	return time.Since(start)
+}
+
+func TestConcurrentCallbacksDontSerialize(t *testing.T) {
+	limiter = newRateLimiter()
+	latency := 200 * time.Millisecond
+	client := NewClient(42)
+	client.BaseUrl = slowServer(t, latency, 1).URL
+
+	elapsed := timeConcurrent(t, 5, func(i int) error {
+		return client.CreateInteractionResponse(context.Background(), strconv.Itoa(1000+i), "token"+strconv.Itoa(i), types.InteractionCallbackMessage{Type: 4})
+	})
+	if elapsed > 2*latency {
+		t.Errorf("5 callbacks took %s, want about %s", elapsed, latency)
+	}
+}
+
+func TestConcurrentRequestsInBucketDontSerialize(t *testing.T) {
+	limiter = newRateLimiter()
+	latency := 200 * time.Millisecond
+	client := NewClient(42)
+	client.BaseUrl = slowServer(t, latency, 10).URL
+
+	// Learn the bucket's limits first, so the concurrent requests are limited by it.
+	_, err := client.EditOriginalResponse(context.Background(), "token", types.InteractionCallbackData{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	elapsed := timeConcurrent(t, 5, func(int) error {
+		_, err := client.EditOriginalResponse(context.Background(), "token", types.InteractionCallbackData{})
+		return err
+	})
+	if elapsed > 2*latency {
+		t.Errorf("5 requests with requests left took %s, want about %s", elapsed, latency)
+	}
+}
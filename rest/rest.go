@@ -7,43 +7,110 @@ import (
 	"log"
 	"mime/multipart"
 	"net/http"
-	"net/http/httputil"
 	"net/textproto"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/haplesspanda/haplessbot/constants"
+	"github.com/haplesspanda/haplessbot/types"
 )
 
+// Longest a request may take, including reading the response. Rate limit waits don't count.
+const requestTimeout = 30 * time.Second
+
 var client *http.Client
 
 func init() {
-	client = &http.Client{}
+	client = &http.Client{Timeout: requestTimeout}
 }
 
-func check(e error) {
-	if e != nil {
-		panic(e)
-	}
-}
+// Retries after a 429 before giving up on a request.
+const maxRetries = 3
 
-func DoJsonRequest(request *http.Request) []byte {
+func DoJsonRequest(request *http.Request) ([]byte, error) {
 	return DoRequest(request, "application/json")
 }
 
-func DoRequest(request *http.Request, contentType string) []byte {
-	appendHeaders(request, contentType)
-	response, err := client.Do(request)
-	check(err)
-
-	dumpedResponse, err := httputil.DumpResponse(response, true)
-	check(err)
-
-	log.Printf("HTTP response: %s", dumpedResponse)
-	defer response.Body.Close()
-	body, err := io.ReadAll(response.Body)
-	check(err)
+// DoRequest sends an authenticated request, waiting out rate limits and retrying requests that get a 429.
+// Non-2xx responses are not an error, the caller gets their body.
+func DoRequest(request *http.Request, contentType string) ([]byte, error) {
+	_, body, err := doRequest(request, contentType)
+	return body, err
+}
 
-	return body
+// Same as DoRequest, also returning the response status code.
+func doRequest(request *http.Request, contentType string) (int, []byte, error) {
+	appendHeaders(request, contentType)
+	ctx := request.Context()
+	route, majors := routeFor(request.Method, request.URL)
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && request.Body != nil {
+			if request.GetBody == nil {
+				return 0, nil, fmt.Errorf("cannot retry %s, request body can't be reread", route)
+			}
+			body, err := request.GetBody()
+			if err != nil {
+				return 0, nil, err
+			}
+			request.Body = body
+		}
+
+		if exempt(route) {
+			response, err := client.Do(request)
+			if err != nil {
+				return 0, nil, err
+			}
+			log.Printf("%s: %s", route, response.Status)
+			body, err := io.ReadAll(response.Body)
+			response.Body.Close()
+			return response.StatusCode, body, err
+		}
+
+		err := limiter.waitGlobal(ctx)
+		if err != nil {
+			return 0, nil, err
+		}
+		bucket := limiter.bucketFor(route, majors)
+		err = bucket.take(ctx)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		response, err := client.Do(request)
+		if err != nil {
+			bucket.cancel()
+			return 0, nil, err
+		}
+
+		log.Printf("%s: %s", route, response.Status)
+		body, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		hash := bucket.done(response.Header)
+		if hash != "" {
+			limiter.setRouteBucket(route, majors, hash, bucket)
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if response.StatusCode != http.StatusTooManyRequests {
+			return response.StatusCode, body, nil
+		}
+
+		retryAfter, global := parseRateLimited(response.Header, body)
+		if global {
+			limiter.setGlobalReset(time.Now().Add(retryAfter))
+		} else {
+			bucket.limited(retryAfter)
+		}
+
+		if attempt >= maxRetries {
+			return 0, nil, fmt.Errorf("rate limited on %s after %d retries", route, maxRetries)
+		}
+		log.Printf("Rate limited on %s (global=%t), retrying in %s", route, global, retryAfter)
+	}
 }
 
 func appendHeaders(request *http.Request, contentType string) {
@@ -53,52 +120,85 @@ func appendHeaders(request *http.Request, contentType string) {
 	request.Header.Set("Content-Type", contentType)
 }
 
+// File to upload with a message. Content comes from Data, Reader or the file at Filename, in that order.
 type BinaryAttachment struct {
 	ContentType string
+	// Filename shown in Discord, and used to refer to it with attachment://
 	Name        string
+	Description string
 	Filename    string
+	Data        []byte
+	Reader      io.Reader
+}
+
+// AttachmentMetadata describes files for the "attachments" array of a message payload, matching the
+// files[n] parts MultiPartForm writes.
+func AttachmentMetadata(attachments []BinaryAttachment) []types.Attachment {
+	result := make([]types.Attachment, len(attachments))
+	for i, attachment := range attachments {
+		result[i] = types.Attachment{
+			Id:          strconv.Itoa(i),
+			Filename:    attachment.Name,
+			Description: attachment.Description,
+		}
+	}
+	return result
 }
 
-func MultiPartForm(jsonData []byte, attachment BinaryAttachment) (*bytes.Buffer, string) {
+func MultiPartForm(jsonData []byte, attachments []BinaryAttachment) (*bytes.Buffer, string, error) {
 	var b bytes.Buffer
 	writer := multipart.NewWriter(&b)
-	var jsonWriter io.Writer
 	jsonHeader := make(textproto.MIMEHeader)
 	jsonHeader.Set("Content-Disposition", "form-data; name=\"payload_json\"")
 	jsonHeader.Set("Content-Type", "application/json")
 	jsonWriter, err := writer.CreatePart(jsonHeader)
 	if err != nil {
-		panic(err)
+		return nil, "", err
 	}
 
 	_, err = jsonWriter.Write(jsonData)
 	if err != nil {
-		panic(err)
+		return nil, "", err
 	}
 
-	var binaryWriter io.Writer
-	binaryHeader := make(textproto.MIMEHeader)
-	binaryHeader.Set("Content-Disposition", fmt.Sprintf("form-data; name=\"file0\"; filename=\"%s\"", attachment.Name))
-	binaryHeader.Set("Content-Type", attachment.ContentType)
-	binaryWriter, err = writer.CreatePart(binaryHeader)
-	if err != nil {
-		panic(err)
+	for i, attachment := range attachments {
+		binaryHeader := make(textproto.MIMEHeader)
+		binaryHeader.Set("Content-Disposition", fmt.Sprintf("form-data; name=\"files[%d]\"; filename=\"%s\"", i, attachment.Name))
+		binaryHeader.Set("Content-Type", attachment.ContentType)
+		binaryWriter, err := writer.CreatePart(binaryHeader)
+		if err != nil {
+			return nil, "", err
+		}
+
+		err = writeAttachment(binaryWriter, attachment)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to write attachment %s: %w", attachment.Name, err)
+		}
 	}
 
-	file, err := os.Open(attachment.Filename)
+	err = writer.Close()
 	if err != nil {
-		panic(err)
+		return nil, "", err
 	}
-	defer file.Close()
+	return &b, writer.Boundary(), nil
+}
 
-	io.Copy(binaryWriter, file)
-	if err != nil {
-		panic(err)
+func writeAttachment(writer io.Writer, attachment BinaryAttachment) error {
+	if attachment.Data != nil {
+		_, err := writer.Write(attachment.Data)
+		return err
+	}
+	if attachment.Reader != nil {
+		_, err := io.Copy(writer, attachment.Reader)
+		return err
 	}
 
-	err = writer.Close()
+	file, err := os.Open(attachment.Filename)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	return &b, writer.Boundary()
+	defer file.Close()
+
+	_, err = io.Copy(writer, file)
+	return err
 }
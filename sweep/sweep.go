@@ -0,0 +1,32 @@
+// Package sweep cleans expired entries out of maps that grow with traffic, like caches and rate limit state, without
+// scanning them on every insert.
+package sweep
+
+// Maps smaller than this aren't worth sweeping.
+const minSize = 1024
+
+// Schedule spaces out sweeps of one map: it's swept once it has grown to twice its size after the last sweep, so each
+// sweep costs about as much as the inserts since the one before. The zero value is ready to use. Guard it with the
+// map's lock.
+type Schedule struct {
+	next int
+}
+
+// Expired deletes the entries that are expired, if the map has grown enough since the last sweep. Call it after
+// adding to the map.
+func Expired[K comparable, V any](schedule *Schedule, entries map[K]V, expired func(V) bool) {
+	if len(entries) < minSize || len(entries) < schedule.next {
+		return
+	}
+	All(entries, expired)
+	schedule.next = 2 * len(entries)
+}
+
+// All deletes the entries that are expired right away, e.g. before saving the map.
+func All[K comparable, V any](entries map[K]V, expired func(V) bool) {
+	for key, value := range entries {
+		if expired(value) {
+			delete(entries, key)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package sweep
+
+import "testing"
+
+func TestExpired(t *testing.T) {
+	var schedule Schedule
+	entries := make(map[int]bool)
+	expired := func(isExpired bool) bool { return isExpired }
+
+	// Nothing is swept until the map reaches minSize.
+	for i := 0; i < minSize-1; i++ {
+		entries[i] = true
+		Expired(&schedule, entries, expired)
+	}
+	if len(entries) != minSize-1 {
+		t.Fatalf("swept %d entries below minSize", minSize-1-len(entries))
+	}
+
+	// Reaching it sweeps the expired entries.
+	entries[-1] = false
+	Expired(&schedule, entries, expired)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries after the sweep, want 1", len(entries))
+	}
+
+	// The next sweep waits for the map to double, but never sweeps below minSize.
+	for i := 0; i < minSize-2; i++ {
+		entries[i] = true
+		Expired(&schedule, entries, expired)
+	}
+	if len(entries) != minSize-1 {
+		t.Fatalf("got %d entries, want no sweep yet", len(entries))
+	}
+	entries[minSize] = true
+	Expired(&schedule, entries, expired)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries after the second sweep, want 1", len(entries))
+	}
+}
+
+func TestExpiredWaitsForGrowth(t *testing.T) {
+	var schedule Schedule
+	entries := make(map[int]bool)
+	never := func(bool) bool { return false }
+
+	// Nothing expires, so the map stays at minSize and the next sweep waits for twice that.
+	for i := 0; i < minSize; i++ {
+		entries[i] = true
+	}
+	Expired(&schedule, entries, never)
+	if schedule.next != 2*minSize {
+		t.Errorf("got next sweep at %d, want %d", schedule.next, 2*minSize)
+	}
+}
@@ -0,0 +1,94 @@
+package types
+
+// Payloads for gateway events (op 0), keyed by event name in package gateway.
+
+type UnavailableGuild struct {
+	Id          string `json:"id"`
+	Unavailable bool   `json:"unavailable"`
+}
+
+type ReadyEvent struct {
+	Version          int                `json:"v"`
+	User             UserData           `json:"user"`
+	Guilds           []UnavailableGuild `json:"guilds"`
+	SessionId        string             `json:"session_id"`
+	ResumeGatewayUrl string             `json:"resume_gateway_url"`
+	Shard            []int              `json:"shard"`
+}
+
+type Message struct {
+	Id          string           `json:"id"`
+	ChannelId   string           `json:"channel_id"`
+	GuildId     string           `json:"guild_id"`
+	Author      UserData         `json:"author"`
+	Member      *GuildMemberData `json:"member"`
+	Content     string           `json:"content"`
+	Timestamp   string           `json:"timestamp"`
+	Attachments []Attachment     `json:"attachments"`
+	Embeds      []Embed          `json:"embeds"`
+	Components  []Component      `json:"components"`
+	// The command the message is a response to.
+	Interaction *MessageInteraction `json:"interaction"`
+}
+
+type MessageInteraction struct {
+	Id   string `json:"id"`
+	Type int    `json:"type"`
+	// Full name of the command, including subcommands, e.g. "fe8 character info".
+	Name string   `json:"name"`
+	User UserData `json:"user"`
+}
+
+type MessageDeleteEvent struct {
+	Id        string `json:"id"`
+	ChannelId string `json:"channel_id"`
+	GuildId   string `json:"guild_id"`
+}
+
+type Guild struct {
+	Id          string            `json:"id"`
+	Name        string            `json:"name"`
+	Icon        *string           `json:"icon"`
+	OwnerId     string            `json:"owner_id"`
+	MemberCount int               `json:"member_count"`
+	Members     []GuildMemberData `json:"members"`
+	Unavailable bool              `json:"unavailable"`
+}
+
+type GuildMemberAddEvent struct {
+	GuildMemberData
+	GuildId string `json:"guild_id"`
+}
+
+type GuildMemberUpdateEvent struct {
+	GuildMemberData
+	GuildId string `json:"guild_id"`
+}
+
+type GuildMemberRemoveEvent struct {
+	GuildId string   `json:"guild_id"`
+	User    UserData `json:"user"`
+}
+
+type Emoji struct {
+	Id       *string `json:"id"`
+	Name     *string `json:"name"`
+	Animated bool    `json:"animated"`
+}
+
+type MessageReactionAddEvent struct {
+	UserId    string           `json:"user_id"`
+	ChannelId string           `json:"channel_id"`
+	MessageId string           `json:"message_id"`
+	GuildId   string           `json:"guild_id"`
+	Member    *GuildMemberData `json:"member"`
+	Emoji     Emoji            `json:"emoji"`
+}
+
+type MessageReactionRemoveEvent struct {
+	UserId    string `json:"user_id"`
+	ChannelId string `json:"channel_id"`
+	MessageId string `json:"message_id"`
+	GuildId   string `json:"guild_id"`
+	Emoji     Emoji  `json:"emoji"`
+}
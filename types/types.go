@@ -11,14 +11,24 @@ type ResolvedEntities struct {
 	Users       map[string]UserData        `json:"users"`
 	Members     map[string]GuildMemberData `json:"members"`
 	Attachments map[string]Attachment      `json:"attachments"`
+	Messages    map[string]Message         `json:"messages"`
 }
 
 type InteractionCreateDetails struct {
-	Token   string          `json:"token"`
-	Data    InteractionData `json:"data"`
-	Member  GuildMemberData `json:"member"`
-	Id      string          `json:"id"`
-	GuildId string          `json:"guild_id"`
+	Type   int             `json:"type"`
+	Token  string          `json:"token"`
+	Data   InteractionData `json:"data"`
+	Member GuildMemberData `json:"member"`
+	// Set instead of Member for interactions in DMs.
+	User      *UserData `json:"user"`
+	Id        string    `json:"id"`
+	GuildId   string    `json:"guild_id"`
+	ChannelId string    `json:"channel_id"`
+	// The message a used component is on.
+	Message *Message `json:"message"`
+	// Language of the user, and of the guild for interactions in guilds, e.g. "en-US" or "de".
+	Locale      string `json:"locale"`
+	GuildLocale string `json:"guild_locale"`
 }
 
 type InteractionData struct {
@@ -27,19 +37,45 @@ type InteractionData struct {
 	Id       string           `json:"id"`
 	Options  []Option         `json:"options"`
 	Resolved ResolvedEntities `json:"resolved"`
+	// For user and message commands, the user or message that was right-clicked. Details are in Resolved.
+	TargetId string `json:"target_id"`
+	// For message components, the clicked component and any chosen select menu values.
+	CustomId      string   `json:"custom_id"`
+	ComponentType int      `json:"component_type"`
+	Values        []string `json:"values"`
+	// For modal submissions, action rows with the submitted text inputs.
+	Components []Component `json:"components"`
 }
 
 type GuildMemberData struct {
 	User   UserData `json:"user"`
 	Avatar *string  `json:"avatar"`
+	// Role IDs.
+	Roles []string `json:"roles"`
+	// Bitfield of the member's permissions in the channel, only set in interactions.
+	Permissions string `json:"permissions"`
 }
 
+// Permission bits, see https://discord.com/developers/docs/topics/permissions.
+const (
+	PermissionAdministrator uint64 = 1 << 3
+)
+
 type UserData struct {
 	Username      string  `json:"username"`
 	Discriminator string  `json:"discriminator"`
 	Id            string  `json:"id"`
 	Avatar        *string `json:"avatar"`
 	Banner        *string `json:"banner"`
+	// Banner color as an RGB integer, only set when fetching the user.
+	AccentColor          *int                  `json:"accent_color"`
+	AvatarDecorationData *AvatarDecorationData `json:"avatar_decoration_data"`
+}
+
+// Frame shown around a user's avatar.
+type AvatarDecorationData struct {
+	Asset string `json:"asset"`
+	SkuId string `json:"sku_id"`
 }
 
 type EmbedImage struct {
@@ -57,24 +93,161 @@ type Embed struct {
 	Url         string         `json:"url"`
 	Image       EmbedImage     `json:"image"`
 	Thumbnail   EmbedThumbnail `json:"thumbnail"`
+	// Color of the embed's left border as an RGB integer.
+	Color int `json:"color,omitempty"`
 }
 
 type Attachment struct {
 	Id          string `json:"id"`
-	Description string `json:"description"`
+	Description string `json:"description,omitempty"`
 	Filename    string `json:"filename"`
-	ContentType string `json:"content_type"`
-	Url         string `json:"url"`
-	Size        int    `json:"size"`
+	ContentType string `json:"content_type,omitempty"`
+	Url         string `json:"url,omitempty"`
+	Size        int    `json:"size,omitempty"`
+}
+
+const (
+	MessageFlagSuppressEmbeds = 1 << 2
+	// Only the user who ran the command can see the message.
+	MessageFlagEphemeral             = 1 << 6
+	MessageFlagSuppressNotifications = 1 << 12
+)
+
+// Which mentions in a message actually notify anyone. Parse takes "roles", "users" and "everyone".
+type AllowedMentions struct {
+	Parse       []string `json:"parse,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Users       []string `json:"users,omitempty"`
+	RepliedUser bool     `json:"replied_user,omitempty"`
+}
+
+const (
+	ComponentTypeActionRow    = 1
+	ComponentTypeButton       = 2
+	ComponentTypeStringSelect = 3
+	ComponentTypeTextInput    = 4
+	ComponentTypeUserSelect   = 5
+)
+
+const (
+	ButtonStylePrimary   = 1
+	ButtonStyleSecondary = 2
+	ButtonStyleSuccess   = 3
+	ButtonStyleDanger    = 4
+	ButtonStyleLink      = 5
+)
+
+const (
+	TextInputStyleShort     = 1
+	TextInputStyleParagraph = 2
+)
+
+// Message component such as an action row, button or select menu, see
+// https://discord.com/developers/docs/interactions/message-components.
+type Component struct {
+	Type int `json:"type"`
+	// Sent back in the interaction when the component is used. Not set for action rows and link buttons.
+	CustomId string `json:"custom_id,omitempty"`
+	Style    int    `json:"style,omitempty"`
+	Label    string `json:"label,omitempty"`
+	Url      string `json:"url,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+	// Select menus only.
+	Options   []SelectOption `json:"options,omitempty"`
+	MinValues *int           `json:"min_values,omitempty"`
+	MaxValues *int           `json:"max_values,omitempty"`
+	// Select menus and text inputs.
+	Placeholder string `json:"placeholder,omitempty"`
+	// Text inputs only. Value is prefilled, and holds what the user entered in modal submissions.
+	Value     string `json:"value,omitempty"`
+	MinLength int    `json:"min_length,omitempty"`
+	MaxLength int    `json:"max_length,omitempty"`
+	// Defaults to true.
+	Required *bool `json:"required,omitempty"`
+	// Children of an action row.
+	Components []Component `json:"components,omitempty"`
+}
+
+type SelectOption struct {
+	Label       string `json:"label"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+	Default     bool   `json:"default,omitempty"`
 }
 
 type InteractionCallbackData struct {
-	Content     string       `json:"content"`
-	Embeds      []Embed      `json:"embeds"`
-	Attachments []Attachment `json:"attachments"`
+	Tts             bool             `json:"tts,omitempty"`
+	Content         string           `json:"content,omitempty"`
+	Embeds          []Embed          `json:"embeds,omitempty"`
+	AllowedMentions *AllowedMentions `json:"allowed_mentions,omitempty"`
+	// Bitfield of MessageFlag values.
+	Flags       int          `json:"flags,omitempty"`
+	Components  []Component  `json:"components,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Modals only, along with Components.
+	CustomId string `json:"custom_id,omitempty"`
+	Title    string `json:"title,omitempty"`
 }
 
 type InteractionCallbackMessage struct {
 	Type int                     `json:"type"`
 	Data InteractionCallbackData `json:"data"`
 }
+
+const (
+	ActivityTypePlaying   = 0
+	ActivityTypeStreaming = 1
+	ActivityTypeListening = 2
+	ActivityTypeWatching  = 3
+	ActivityTypeCustom    = 4
+	ActivityTypeCompeting = 5
+)
+
+type Activity struct {
+	Name  string `json:"name"`
+	Type  int    `json:"type"`
+	State string `json:"state,omitempty"`
+	Url   string `json:"url,omitempty"`
+}
+
+type PresenceUpdate struct {
+	Since      *int64     `json:"since"`
+	Activities []Activity `json:"activities"`
+	Status     string     `json:"status"`
+	Afk        bool       `json:"afk"`
+}
+
+type ApplicationCommandOptionChoice struct {
+	Name              string            `json:"name"`
+	NameLocalizations map[string]string `json:"name_localizations,omitempty"`
+	Value             any               `json:"value"`
+}
+
+type ApplicationCommandOption struct {
+	Type        int    `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Translations by locale, e.g. "de" or "es-ES".
+	NameLocalizations        map[string]string                `json:"name_localizations,omitempty"`
+	DescriptionLocalizations map[string]string                `json:"description_localizations,omitempty"`
+	Required                 bool                             `json:"required,omitempty"`
+	Choices                  []ApplicationCommandOptionChoice `json:"choices,omitempty"`
+	Options                  []ApplicationCommandOption       `json:"options,omitempty"`
+}
+
+type ApplicationCommand struct {
+	Id            string `json:"id,omitempty"`
+	ApplicationId string `json:"application_id,omitempty"`
+	GuildId       string `json:"guild_id,omitempty"`
+	Type          int    `json:"type,omitempty"`
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	// Translations by locale, e.g. "de" or "es-ES".
+	NameLocalizations        map[string]string          `json:"name_localizations,omitempty"`
+	DescriptionLocalizations map[string]string          `json:"description_localizations,omitempty"`
+	Options                  []ApplicationCommandOption `json:"options,omitempty"`
+	// Permission bitfield members need to see the command unless server admins say otherwise. Nil for everyone,
+	// "0" for admins only.
+	DefaultMemberPermissions *string `json:"default_member_permissions,omitempty"`
+	Version                  string  `json:"version,omitempty"`
+}
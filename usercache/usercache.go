@@ -0,0 +1,95 @@
+package usercache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/haplesspanda/haplessbot/sweep"
+	"github.com/haplesspanda/haplessbot/types"
+)
+
+// Cache keeps users for a while after they're looked up over REST.
+//
+// Users in gateway events are partial (Discord leaves out profile fields like the banner and accent color), so they
+// aren't cached, but updates to them drop the cached user.
+type Cache struct {
+	ttl     time.Duration
+	entries map[string]entry
+	lock    sync.Mutex
+	sweeps  sweep.Schedule
+
+	hits          int64
+	misses        int64
+	invalidations int64
+}
+
+type entry struct {
+	user    types.UserData
+	expires time.Time
+}
+
+// Stats counts how lookups went since the cache was created.
+type Stats struct {
+	// Lookups answered from the cache.
+	Hits int64
+	// Lookups that found no unexpired user.
+	Misses int64
+	// Cached users dropped because they changed.
+	Invalidations int64
+	// Users cached right now, including expired ones not swept yet.
+	Entries int
+}
+
+// New creates a cache keeping users for ttl.
+func New(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:     ttl,
+		entries: make(map[string]entry),
+	}
+}
+
+// Get returns the cached user if it hasn't expired.
+func (c *Cache) Get(userId string) (types.UserData, bool) {
+	c.lock.Lock()
+	cached, ok := c.entries[userId]
+	c.lock.Unlock()
+
+	if !ok || !time.Now().Before(cached.expires) {
+		atomic.AddInt64(&c.misses, 1)
+		return types.UserData{}, false
+	}
+	atomic.AddInt64(&c.hits, 1)
+	return cached.user, true
+}
+
+// Put caches a user looked up over REST.
+func (c *Cache) Put(user types.UserData) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := time.Now()
+	c.entries[user.Id] = entry{user: user, expires: now.Add(c.ttl)}
+	sweep.Expired(&c.sweeps, c.entries, func(cached entry) bool { return !now.Before(cached.expires) })
+}
+
+// Invalidate drops the user from the cache, e.g. when a gateway event says they changed.
+func (c *Cache) Invalidate(userId string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.entries[userId]; ok {
+		delete(c.entries, userId)
+		atomic.AddInt64(&c.invalidations, 1)
+	}
+}
+
+func (c *Cache) Stats() Stats {
+	c.lock.Lock()
+	entries := len(c.entries)
+	c.lock.Unlock()
+	return Stats{
+		Hits:          atomic.LoadInt64(&c.hits),
+		Misses:        atomic.LoadInt64(&c.misses),
+		Invalidations: atomic.LoadInt64(&c.invalidations),
+		Entries:       entries,
+	}
+}
@@ -0,0 +1,120 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Pool runs tasks on a fixed number of goroutines, with a bounded queue of tasks waiting for a worker.
+type Pool struct {
+	tasks   chan task
+	timeout time.Duration
+	pending sync.WaitGroup
+	closed  bool
+	lock    sync.RWMutex
+}
+
+type task struct {
+	name string
+	run  func(ctx context.Context)
+}
+
+// NewPool starts size workers. Each task gets a context cancelled after timeout, or never if timeout is zero.
+func NewPool(size int, queueDepth int, timeout time.Duration) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	if queueDepth < 0 {
+		queueDepth = 0
+	}
+
+	pool := &Pool{
+		tasks:   make(chan task, queueDepth),
+		timeout: timeout,
+	}
+	for i := 0; i < size; i++ {
+		go pool.work()
+	}
+	return pool
+}
+
+// Submit queues a task, returning false without running it if the queue is full or the pool is shut down.
+func (p *Pool) Submit(name string, run func(ctx context.Context)) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.closed {
+		return false
+	}
+
+	p.pending.Add(1)
+	select {
+	case p.tasks <- task{name: name, run: run}:
+		return true
+	default:
+		p.pending.Done()
+		return false
+	}
+}
+
+// Tasks waiting for a free worker.
+func (p *Pool) QueueDepth() int {
+	return len(p.tasks)
+}
+
+// Shutdown stops accepting tasks and waits for queued and running ones. Returns false if they did not finish in time.
+func (p *Pool) Shutdown(timeout time.Duration) bool {
+	p.lock.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.tasks)
+	}
+	p.lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (p *Pool) work() {
+	for t := range p.tasks {
+		p.run(t)
+	}
+}
+
+func (p *Pool) run(t task) {
+	defer p.pending.Done()
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if p.timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), p.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+
+	// A failing task shouldn't take the worker (or the whole bot) down with it.
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovered panic in %s: %v\n%s", t.name, r, debug.Stack())
+		}
+	}()
+
+	start := time.Now()
+	t.run(ctx)
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Printf("%s ran past its %s timeout (took %s)", t.name, p.timeout, time.Since(start))
+	}
+}